@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+var historyStation int
+var historyDate string
+
+// newHistoryCommand creates the history subcommand.
+func newHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the revision history of a weather record",
+		Long: `Show how the values of a single weather record changed between syncs.
+
+Revisions are recorded when 'knmi sync --upsert' or '--revise-days' replaces
+values that KNMI corrected after quality control.`,
+		Example: "  knmi history --station 260 --date 2024-07-01",
+		RunE:    runHistory,
+	}
+
+	cmd.Flags().IntVar(&historyStation, "station", 0, "Station number")
+	cmd.Flags().StringVar(&historyDate, "date", "", "Observation date (YYYY-MM-DD)")
+	_ = cmd.MarkFlagRequired("station")
+	_ = cmd.MarkFlagRequired("date")
+
+	return cmd
+}
+
+// runHistory executes the history command.
+func runHistory(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	date, err := time.Parse("2006-01-02", historyDate)
+	if err != nil {
+		return fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", historyDate)
+	}
+
+	cfg := GetConfig()
+	dbURL := cfg.DatabaseURL
+	if databaseURL != "" {
+		dbURL = databaseURL
+	}
+
+	if dbURL == "" {
+		return fmt.Errorf("database URL not configured (set DATABASE_URL or use --database-url)")
+	}
+
+	LogVerbose("Connecting to database...")
+	database, err := db.Connect(ctx, dbURL)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer database.Close()
+
+	tableExists, err := db.TableExists(ctx, database, "weather_record_revisions")
+	if err != nil {
+		return fmt.Errorf("checking database state: %w", err)
+	}
+	if !tableExists {
+		return fmt.Errorf("revision history not available. Run 'knmi migrate' first")
+	}
+
+	repo := db.NewWeatherRepository(database)
+	current, err := repo.GetRecord(ctx, historyStation, date)
+	if err != nil {
+		return fmt.Errorf("getting record: %w", err)
+	}
+	revisions, err := repo.GetRevisions(ctx, historyStation, date)
+	if err != nil {
+		return fmt.Errorf("getting revisions: %w", err)
+	}
+
+	if current == nil && len(revisions) == 0 {
+		fmt.Printf("No record found for station %d on %s\n", historyStation, historyDate)
+		return nil
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("No revisions recorded for station %d on %s\n", historyStation, historyDate)
+		return nil
+	}
+
+	var currentValues map[string]*int
+	if current != nil {
+		currentValues = db.MeasurementValues(current)
+	}
+
+	fmt.Printf("Station %d, %s: %d revisions\n", historyStation, historyDate, len(revisions))
+	fmt.Println()
+	fmt.Printf("%-23s %s\n", "SYNCED AT", "CHANGES")
+
+	for i, rev := range revisions {
+		// The value a revision changed to is the one saved by the next
+		// revision, or the current value for the latest one
+		next := currentValues
+		if i+1 < len(revisions) {
+			next = revisions[i+1].Previous
+		}
+
+		changes := make([]string, len(rev.ChangedColumns))
+		for j, column := range rev.ChangedColumns {
+			changes[j] = fmt.Sprintf("%s %s -> %s",
+				strings.ToUpper(column),
+				formatColumnValue(column, rev.Previous[column]),
+				formatColumnValue(column, next[column]),
+			)
+		}
+
+		fmt.Printf("%-23s %s\n", rev.SyncedAt.Format("2006-01-02 15:04:05 MST"), strings.Join(changes, ", "))
+	}
+
+	return nil
+}
+
+// formatColumnValue formats a raw value of a weather_records column in the
+// column's physical unit, e.g. "18.5 °C". Columns that are not described by
+// parser.WeatherColumns, such as trace flags, are shown as stored.
+func formatColumnValue(column string, v *int) string {
+	info, ok := parser.LookupWeatherColumn(column)
+	if v == nil || !ok {
+		return formatPreviewValue(v)
+	}
+	return info.Format(*v)
+}
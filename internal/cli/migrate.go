@@ -42,7 +42,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 
 	// Connect to database
 	LogVerbose("Connecting to database...")
-	database, err := db.Connect(dbURL)
+	database, err := db.Connect(cmd.Context(), dbURL)
 	if err != nil {
 		return fmt.Errorf("connecting to database: %w", err)
 	}
@@ -63,7 +63,7 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	}
 
 	runner := migration.NewRunner(database, logFn)
-	result, err := runner.Run(dir)
+	result, err := runner.Run(cmd.Context(), dir)
 	if err != nil {
 		return err
 	}
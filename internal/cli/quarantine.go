@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var quarantineStation int
+
+// newQuarantineCommand creates the quarantine subcommand.
+func newQuarantineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quarantine",
+		Short: "Review daily records held back by validation",
+		Long: `Review the daily records that failed a plausibility check with severity
+quarantine during 'knmi sync' (see --validation and --rule-severity).
+
+Quarantined records are kept in weather_records_quarantine together with the
+rules they broke. Release a record to move it into weather_records, or drop
+it to discard it. Releasing a revision of a record that is already stored
+updates it, keeping the previous values in weather_record_revisions.`,
+	}
+
+	list := &cobra.Command{
+		Use:     "list",
+		Short:   "List quarantined records and the rules they broke",
+		Example: "  knmi quarantine list --station 260",
+		Args:    cobra.NoArgs,
+		RunE:    runQuarantineList,
+	}
+	list.Flags().IntVar(&quarantineStation, "station", 0, "Only list the records of this station")
+	cmd.AddCommand(list)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "release <id>",
+		Short:   "Move a quarantined record into weather_records",
+		Example: "  knmi quarantine release 12",
+		Args:    cobra.ExactArgs(1),
+		RunE:    runQuarantineRelease,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "drop <id>",
+		Short:   "Discard a quarantined record",
+		Example: "  knmi quarantine drop 12",
+		Args:    cobra.ExactArgs(1),
+		RunE:    runQuarantineDrop,
+	})
+
+	return cmd
+}
+
+// openQuarantine connects to the database and returns the quarantine
+// repository, checking that its table has been created. The caller closes
+// the returned database.
+func openQuarantine(ctx context.Context) (*sql.DB, *db.QuarantineRepository, error) {
+	cfg := GetConfig()
+	dbURL := cfg.DatabaseURL
+	if databaseURL != "" {
+		dbURL = databaseURL
+	}
+
+	if dbURL == "" {
+		return nil, nil, fmt.Errorf("database URL not configured (set DATABASE_URL or use --database-url)")
+	}
+
+	LogVerbose("Connecting to database...")
+	database, err := db.Connect(ctx, dbURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to database: %w", err)
+	}
+
+	repo := db.NewQuarantineRepository(database)
+	tableExists, err := repo.TableExists(ctx)
+	if err != nil {
+		database.Close()
+		return nil, nil, fmt.Errorf("checking database state: %w", err)
+	}
+	if !tableExists {
+		database.Close()
+		return nil, nil, fmt.Errorf("weather_records_quarantine table not found. Run 'knmi migrate' first")
+	}
+
+	return database, repo, nil
+}
+
+// parseQuarantineID parses the record ID argument of a quarantine command.
+func parseQuarantineID(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid record ID %q (expected a positive integer)", arg)
+	}
+	return id, nil
+}
+
+// runQuarantineList executes the quarantine list command.
+func runQuarantineList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	database, repo, err := openQuarantine(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	records, err := repo.List(ctx, quarantineStation)
+	if err != nil {
+		return fmt.Errorf("listing quarantined records: %w", err)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No quarantined records")
+		return nil
+	}
+
+	fmt.Printf("%-6s %-7s %-10s %-19s %s\n", "ID", "STATION", "DATE", "QUARANTINED AT", "RULES")
+	for _, q := range records {
+		reasons := make([]string, len(q.Rules))
+		for i, rule := range q.Rules {
+			reasons[i] = rule
+			if i < len(q.Messages) {
+				reasons[i] += ": " + q.Messages[i]
+			}
+		}
+
+		fmt.Printf("%-6d %-7d %-10s %-19s %s\n",
+			q.ID,
+			q.Record.StationID,
+			q.Record.Date.Format("2006-01-02"),
+			q.QuarantinedAt.Local().Format("2006-01-02 15:04:05"),
+			strings.Join(reasons, "; "),
+		)
+	}
+
+	return nil
+}
+
+// runQuarantineRelease executes the quarantine release command.
+func runQuarantineRelease(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	id, err := parseQuarantineID(args[0])
+	if err != nil {
+		return err
+	}
+
+	database, repo, err := openQuarantine(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	q, err := repo.Release(ctx, id)
+	if err != nil {
+		return fmt.Errorf("releasing record %d: %w", id, err)
+	}
+
+	fmt.Printf("Released record %d (station %d, %s) into weather_records\n",
+		id, q.Record.StationID, q.Record.Date.Format("2006-01-02"))
+	return nil
+}
+
+// runQuarantineDrop executes the quarantine drop command.
+func runQuarantineDrop(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	id, err := parseQuarantineID(args[0])
+	if err != nil {
+		return err
+	}
+
+	database, repo, err := openQuarantine(ctx)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if err := repo.Drop(ctx, id); err != nil {
+		return fmt.Errorf("dropping record %d: %w", id, err)
+	}
+
+	fmt.Printf("Dropped quarantined record %d\n", id)
+	return nil
+}
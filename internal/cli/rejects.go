@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// rejectFileHeader is the header line of a --reject-file report.
+var rejectFileHeader = []string{"source_url", "file_name", "line_number", "column_name", "reason", "line"}
+
+// rejectReport records the data lines skipped in lenient parse mode, either
+// in the CSV file set by --reject-file or in the parse_rejects table.
+type rejectReport struct {
+	dataset string
+	repo    *db.ParseRejectRepository
+	file    *os.File
+	writer  *csv.Writer
+	count   int
+}
+
+// newRejectReport creates the report for a sync of the dataset stored in
+// table. It returns nil in strict parse mode. Without --reject-file the
+// rejects are stored in the database; if database is nil, as in a dry run,
+// they are only counted.
+func newRejectReport(ctx context.Context, database *sql.DB, table string) (*rejectReport, error) {
+	mode, err := parser.ParseModeFromName(parseModeName)
+	if err != nil {
+		return nil, err
+	}
+	if mode != parser.ModeLenient {
+		return nil, nil
+	}
+
+	report := &rejectReport{dataset: table}
+	switch {
+	case rejectFile != "":
+		report.file, err = os.Create(rejectFile)
+		if err != nil {
+			return nil, fmt.Errorf("creating reject file: %w", err)
+		}
+		report.writer = csv.NewWriter(report.file)
+		if err := report.writer.Write(rejectFileHeader); err != nil {
+			report.file.Close()
+			return nil, fmt.Errorf("writing reject file: %w", err)
+		}
+	case database != nil:
+		report.repo = db.NewParseRejectRepository(database)
+		tableExists, err := report.repo.TableExists(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("checking database state: %w", err)
+		}
+		if !tableExists {
+			return nil, fmt.Errorf("parse_rejects table not found. Run 'knmi migrate' first")
+		}
+	}
+
+	return report, nil
+}
+
+// add records the lines rejected while reading the data file fileName of
+// the archive at url. A nil report is ignored.
+func (r *rejectReport) add(ctx context.Context, url, fileName string, rejects []*parser.ParseError) error {
+	if r == nil || len(rejects) == 0 {
+		return nil
+	}
+	r.count += len(rejects)
+
+	if r.writer != nil {
+		for _, rej := range rejects {
+			row := []string{url, fileName, strconv.Itoa(rej.Line), rej.Column, rej.Err.Error(), rej.Text}
+			if err := r.writer.Write(row); err != nil {
+				return fmt.Errorf("writing reject file: %w", err)
+			}
+		}
+		r.writer.Flush()
+		if err := r.writer.Error(); err != nil {
+			return fmt.Errorf("writing reject file: %w", err)
+		}
+		return nil
+	}
+
+	if r.repo == nil {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]db.ParseReject, len(rejects))
+	for i, rej := range rejects {
+		rows[i] = db.ParseReject{
+			Dataset:    r.dataset,
+			SourceURL:  url,
+			FileName:   fileName,
+			LineNumber: rej.Line,
+			Column:     rej.Column,
+			Reason:     rej.Err.Error(),
+			Line:       rej.Text,
+			RejectedAt: now,
+		}
+	}
+	if err := r.repo.RecordRejects(ctx, rows); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+// close closes the reject file, if any, and prints how many lines were
+// rejected and where they were recorded. Rejects are flushed as they are
+// added, so closing the file cannot lose any. A nil report is ignored.
+func (r *rejectReport) close() {
+	if r == nil {
+		return
+	}
+	if r.file != nil {
+		r.file.Close()
+	}
+
+	switch {
+	case r.count == 0:
+	case r.file != nil:
+		fmt.Printf("Rejected %d malformed lines (written to %s)\n", r.count, rejectFile)
+	case r.repo != nil:
+		fmt.Printf("Rejected %d malformed lines (recorded in parse_rejects)\n", r.count)
+	default:
+		fmt.Printf("Rejected %d malformed lines\n", r.count)
+	}
+}
@@ -2,8 +2,11 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/harrybawsac/knmi-go/internal/config"
 	"github.com/joho/godotenv"
@@ -30,11 +33,24 @@ The tool supports:
   - Database migrations for schema management
   - Incremental data sync (only new records are inserted)
   - Configurable data source URL
+  - Syncing multiple stations in one run
+  - Daily and hourly observations
+  - Volunteer precipitation stations
+  - Station metadata (names and coordinates)
+  - The KNMI Open Data API as an alternative source
 
 Environment Variables:
   DATABASE_URL         PostgreSQL connection string
-  KNMI_DATA_URL        Override default KNMI data URL
-  KNMI_MIGRATIONS_DIR  Path to migrations directory`,
+  KNMI_DATA_URL        Override default KNMI data URL (may contain {station})
+  KNMI_HOURLY_URL      Override default KNMI hourly data URL (may contain {station} and {decade})
+  KNMI_PRECIPITATION_URL  Override default KNMI precipitation station URL (may contain {station})
+  KNMI_STATIONS        Comma-separated station numbers to sync (default 260)
+  KNMI_MIGRATIONS_DIR  Path to migrations directory
+  KNMI_CACHE_DIR       Directory to keep downloaded archives in
+  KNMI_API_KEY         API key for the KNMI Open Data API (--source opendata)
+  KNMI_OPENDATA_URL    Override the KNMI Open Data API base URL
+  KNMI_OPENDATA_DATASET  Open Data API dataset to fetch archives from
+  KNMI_OPENDATA_VERSION  Version of the Open Data API dataset`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
@@ -56,14 +72,26 @@ Meteorological Institute) and sync it to a PostgreSQL database.`,
 	// Add subcommands
 	cmd.AddCommand(newMigrateCommand())
 	cmd.AddCommand(newSyncCommand())
+	cmd.AddCommand(newHistoryCommand())
+	cmd.AddCommand(newStationsCommand())
+	cmd.AddCommand(newQuarantineCommand())
 	cmd.AddCommand(newVersionCommand())
 
 	return cmd
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// SIGINT and SIGTERM cancel the command's context so it can stop cleanly;
+// a second signal terminates the process immediately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
@@ -79,6 +107,9 @@ func init() {
 	// Add subcommands
 	rootCmd.AddCommand(newMigrateCommand())
 	rootCmd.AddCommand(newSyncCommand())
+	rootCmd.AddCommand(newHistoryCommand())
+	rootCmd.AddCommand(newStationsCommand())
+	rootCmd.AddCommand(newQuarantineCommand())
 	rootCmd.AddCommand(newVersionCommand())
 }
 
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// newStationsCommand creates the stations subcommand.
+func newStationsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stations",
+		Short: "Manage weather station metadata",
+		Long: `Inspect the weather stations known to the database.
+
+Station names and coordinates are taken from the header of the KNMI data
+files during 'knmi sync'.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Short:   "List stations and the range of their daily observations",
+		Example: "  knmi stations list",
+		Args:    cobra.NoArgs,
+		RunE:    runStationsList,
+	})
+
+	return cmd
+}
+
+// runStationsList executes the stations list command.
+func runStationsList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg := GetConfig()
+	dbURL := cfg.DatabaseURL
+	if databaseURL != "" {
+		dbURL = databaseURL
+	}
+
+	if dbURL == "" {
+		return fmt.Errorf("database URL not configured (set DATABASE_URL or use --database-url)")
+	}
+
+	LogVerbose("Connecting to database...")
+	database, err := db.Connect(ctx, dbURL)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer database.Close()
+
+	repo, err := stationRepository(ctx, database)
+	if err != nil {
+		return err
+	}
+
+	stations, err := repo.ListStations(ctx)
+	if err != nil {
+		return fmt.Errorf("listing stations: %w", err)
+	}
+
+	if len(stations) == 0 {
+		fmt.Println("No stations found. Run 'knmi sync' first")
+		return nil
+	}
+
+	fmt.Printf("%-5s %-24s %8s %8s %7s %-10s %-10s\n", "ID", "NAME", "LON", "LAT", "ALT", "FIRST", "LAST")
+	for _, st := range stations {
+		fmt.Printf("%-5d %-24s %8s %8s %7s %-10s %-10s\n",
+			st.ID,
+			formatStationName(st.Name),
+			formatCoordinate(st.Longitude, "%.3f"),
+			formatCoordinate(st.Latitude, "%.3f"),
+			formatCoordinate(st.Altitude, "%.2f"),
+			formatStationDate(st.FirstDate),
+			formatStationDate(st.LastDate),
+		)
+	}
+
+	return nil
+}
+
+// formatStationName formats a station name, or "-" if it is unknown.
+func formatStationName(name *string) string {
+	if name == nil {
+		return "-"
+	}
+	return *name
+}
+
+// formatCoordinate formats an optional coordinate, or "-" if it is unknown.
+func formatCoordinate(v *float64, format string) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *v)
+}
+
+// formatStationDate formats an optional date, or "-" if there is none.
+func formatStationDate(d *time.Time) string {
+	if d == nil {
+		return "-"
+	}
+	return d.Format("2006-01-02")
+}
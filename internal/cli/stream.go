@@ -0,0 +1,395 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/config"
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// streamBatchSize is the number of records read from a data file before
+// they are written to the database. It bounds the memory a sync uses
+// regardless of the size of the file.
+const streamBatchSize = 5000
+
+// stdinInput is the --input value that reads data from standard input.
+const stdinInput = fetch.StdinURL
+
+// previewSize is the number of records shown by a dry run.
+const previewSize = 10
+
+// newFetchClient creates the download client configured by --retries,
+// --timeout, --cache-dir, --max-extracted-mb and --entry. Retries are
+// reported in verbose mode.
+func newFetchClient() *fetch.Client {
+	client := fetch.NewClient(timeout, retries)
+	client.MaxExtractedSize = maxExtractedMB << 20
+	if maxExtractedMB == 0 {
+		client.MaxExtractedSize = -1
+	}
+	client.DataFilePattern = entryPattern
+	client.OnRetry = func(attempt int, delay time.Duration, err error) {
+		LogVerbose("Download failed: %v. Retrying in %s (attempt %d of %d)...", err, delay.Round(time.Millisecond), attempt, retries+1)
+	}
+	if dir := cacheDirectory(GetConfig()); dir != "" {
+		client.Cache = fetch.NewCache(dir)
+	}
+	return client
+}
+
+// sourceOptions returns the configuration of the data sources, as set by
+// the sync flags and environment variables.
+func sourceOptions() fetch.SourceOptions {
+	cfg := GetConfig()
+	return fetch.SourceOptions{
+		Client:          newFetchClient(),
+		Offline:         isOffline(),
+		Stdin:           os.Stdin,
+		APIKey:          cfg.APIKey,
+		OpenDataURL:     cfg.OpenDataURL,
+		OpenDataDataset: cfg.OpenDataDataset,
+		OpenDataVersion: cfg.OpenDataVersion,
+		Logf:            LogVerbose,
+	}
+}
+
+// cacheDirectory returns the archive cache directory set by --cache-dir or
+// KNMI_CACHE_DIR, with a leading "~" expanded to the home directory. It
+// returns "" if archives are not cached.
+func cacheDirectory(cfg *config.Config) string {
+	dir := cfg.CacheDir
+	if cacheDir != "" {
+		dir = cacheDir
+	}
+
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	return dir
+}
+
+// isOffline reports whether archives are read from the cache instead of
+// being downloaded.
+func isOffline() bool {
+	return fromCache || offline
+}
+
+// openData fetches the archive at url from the source its scheme selects
+// and opens its data file. With a source tracker the download is
+// conditional on the file having changed since it was last synced (unless
+// --force is given), returning an error wrapping fetch.ErrNotModified
+// otherwise. In offline mode the newest cached copy is opened instead. The
+// archive is verified against --sha256 or --checksum-sidecar before a
+// downloaded copy is stored in the cache.
+func openData(ctx context.Context, url string, tracker *sourceTracker) (*fetch.Archive, error) {
+	source, err := fetch.NewSource(url, sourceOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	var since fetch.Validators
+	if tracker != nil && !force {
+		src, err := tracker.sources.GetSource(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if src != nil {
+			since = fetch.Validators{ETag: src.ETag, LastModified: src.LastModified}
+		}
+	}
+
+	archive, err := source.Fetch(ctx, url, since)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyArchive(ctx, source, url, archive); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	if err := archive.SaveToCache(); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// verifyArchive checks an archive against the SHA-256 hash pinned with
+// --sha256, or published in a "<url>.sha256" file with --checksum-sidecar.
+func verifyArchive(ctx context.Context, source fetch.Source, url string, archive *fetch.Archive) error {
+	expected := pinnedSHA256
+	if checksumSidecar {
+		sidecarURL := url + ".sha256"
+		LogVerbose("Downloading checksum from %s...", sidecarURL)
+		data, err := source.Download(ctx, sidecarURL)
+		if err != nil {
+			return fmt.Errorf("failed to download checksum: %w", err)
+		}
+		expected, err = fetch.ParseChecksum(data)
+		if err != nil {
+			return fmt.Errorf("invalid checksum file %s: %w", sidecarURL, err)
+		}
+	}
+
+	if expected == "" {
+		return nil
+	}
+	if err := archive.Verify(expected); err != nil {
+		return fmt.Errorf("verifying %s: %w", url, err)
+	}
+	LogVerbose("Verified SHA-256 %s", expected)
+	return nil
+}
+
+// streamRecords reads every data file of the archive at url and passes
+// their records to handle in batches of at most streamBatchSize. A batch
+// holds records of a single data file, described by the reader passed
+// along. A data file without records is passed as an empty batch once its
+// header has been read, so handle sees the station metadata of every file.
+// The batch is reused between calls, so handle must not keep it.
+// It returns the combined result of all batches; on error, that of the
+// batches handled before it.
+//
+// With a source tracker the file is skipped if it has not changed since the
+// last sync, and once all records were handled its version is saved and the
+// run is recorded for station, or without a station if it is 0. Streaming
+// stops before the next batch once ctx is cancelled, in which case neither
+// is recorded.
+//
+// With a reject report the files are parsed in lenient mode: malformed lines
+// are skipped and recorded in the report instead of failing the file.
+func streamRecords[T any](ctx context.Context, url string, station int, tracker *sourceTracker, rejects *rejectReport, newReader func(io.Reader) *parser.Reader[T], handle func(reader *parser.Reader[T], batch []T) (*db.InsertResult, error)) (*db.InsertResult, error) {
+	startedAt := time.Now()
+	archive, err := openData(ctx, url, tracker)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	batch := make([]T, 0, streamBatchSize)
+	count := 0
+	result := &db.InsertResult{}
+	for {
+		n, err := streamFile(ctx, url, archive, batch, result, rejects, newReader, handle)
+		if err != nil {
+			return result, err
+		}
+		count += n
+
+		err = archive.NextFile()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if tracker != nil {
+		run := db.SyncRun{
+			StationID:    station,
+			SourceURL:    url,
+			SourceSHA256: archive.SHA256(),
+			SourceSize:   archive.Size,
+			RecordsRead:  count,
+			Inserted:     result.Inserted,
+			Updated:      result.Updated,
+			StartedAt:    startedAt,
+			FinishedAt:   time.Now(),
+		}
+		if err := tracker.finish(ctx, run, archive.Validators); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// streamFile reads the data file the archive is positioned at, passing its
+// records to handle in batches and adding their results to result. If the
+// file holds no records, handle is called once with an empty batch. It
+// returns the number of records read.
+func streamFile[T any](ctx context.Context, url string, archive *fetch.Archive, batch []T, result *db.InsertResult, rejects *rejectReport, newReader func(io.Reader) *parser.Reader[T], handle func(reader *parser.Reader[T], batch []T) (*db.InsertResult, error)) (int, error) {
+	source := url
+	if name := archive.Name(); name != "" {
+		source = fmt.Sprintf("%s (%s)", url, name)
+	}
+	LogVerbose("Parsing %s...", source)
+
+	reader := newReader(archive)
+	if rejects != nil {
+		reader.SetMode(parser.ModeLenient)
+	}
+	batch = batch[:0]
+	count := 0
+	rejected := 0
+	handled := false
+
+	// flush handles the batch, then records the lines rejected while
+	// reading it, so rejects are only kept for records that were stored.
+	// The last flush of a file without records handles the empty batch.
+	flush := func(last bool) error {
+		if len(batch) > 0 || (last && !handled) {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("reading %s: %w", source, context.Cause(ctx))
+			}
+			batchResult, err := handle(reader, batch)
+			if err != nil {
+				return err
+			}
+			if batchResult != nil {
+				result.Add(batchResult)
+			}
+			batch = batch[:0]
+			handled = true
+		}
+
+		taken := reader.TakeRejects()
+		rejected += len(taken)
+		return rejects.add(ctx, url, archive.Name(), taken)
+	}
+
+	for reader.Next() {
+		if count == 0 {
+			warnUnknownColumns(source, reader.UnknownColumns())
+		}
+		batch = append(batch, reader.Record())
+		count++
+
+		if len(batch) == streamBatchSize {
+			if err := flush(false); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return count, fmt.Errorf("failed to parse CSV in %s: %w", source, err)
+	}
+
+	if err := flush(true); err != nil {
+		return count, err
+	}
+	if rejected > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: rejected %d malformed lines in %s\n", rejected, source)
+	}
+	LogVerbose("Parsed %d rows", count)
+
+	return count, nil
+}
+
+// sourceTracker records the data files a sync processed: their version, so
+// unchanged files can be skipped next time, and a sync run for lineage.
+type sourceTracker struct {
+	sources *db.SourceRepository
+	runs    *db.SyncRunRepository
+	dataset string
+}
+
+// newSourceTracker creates a tracker for the dataset stored in table,
+// checking that its tables have been created.
+func newSourceTracker(ctx context.Context, database *sql.DB, table string) (*sourceTracker, error) {
+	tracker := &sourceTracker{
+		sources: db.NewSourceRepository(database),
+		runs:    db.NewSyncRunRepository(database),
+		dataset: table,
+	}
+
+	tables := []struct {
+		name   string
+		exists func(ctx context.Context) (bool, error)
+	}{
+		{"sources", tracker.sources.TableExists},
+		{"sync_runs", tracker.runs.TableExists},
+	}
+	for _, table := range tables {
+		ok, err := table.exists(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("checking database state: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s table not found. Run 'knmi migrate' first", table.name)
+		}
+	}
+
+	return tracker, nil
+}
+
+// finish records a completed sync run and the version of its source file
+// in one transaction, so the file is never marked as synced without its
+// run, or the other way round.
+func (t *sourceTracker) finish(ctx context.Context, run db.SyncRun, validators fetch.Validators) error {
+	run.Dataset = t.dataset
+
+	tx, err := t.runs.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := t.runs.RecordRunTx(ctx, tx, run); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if !validators.IsZero() {
+		err := t.sources.SaveSourceTx(ctx, tx, db.Source{
+			URL:          run.SourceURL,
+			ETag:         validators.ETag,
+			LastModified: validators.LastModified,
+			SyncedAt:     run.FinishedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error: committing transaction: %w", err)
+	}
+	LogVerbose("Recorded sync of %s (SHA-256 %s)", run.SourceURL, run.SourceSHA256)
+	return nil
+}
+
+// preview keeps the last records passed to it and counts all of them,
+// so a dry run can summarise a file without holding it in memory.
+type preview[T any] struct {
+	records []T
+	total   int
+}
+
+// add records a batch of records.
+func (p *preview[T]) add(records []T) {
+	p.total += len(records)
+	p.records = append(p.records, records...)
+	if len(p.records) > previewSize {
+		p.records = append(p.records[:0], p.records[len(p.records)-previewSize:]...)
+	}
+}
+
+// interrupted reports how far a sync got when ctx was cancelled, e.g. by
+// Ctrl-C or SIGTERM, and returns the error ending it. Each batch is written
+// in one transaction, covering its inserts, its quarantined records and,
+// with --upsert, its updates and revision rows, so batches committed before
+// the interruption are kept and the batch being written is rolled back as
+// a whole. The interrupted file is not recorded as synced, so the next
+// sync reads it again and picks up where this one stopped. The records
+// updated are reported with --upsert.
+func interrupted(ctx context.Context, synced, total, inserted, updated int, name string) error {
+	if isUpsertMode() {
+		fmt.Printf("Interrupted: synced %d of %d stations, %d new %s inserted, %d updated\n", synced, total, inserted, name, updated)
+	} else {
+		fmt.Printf("Interrupted: synced %d of %d stations, %d new %s inserted\n", synced, total, inserted, name)
+	}
+	return fmt.Errorf("sync interrupted: %w", context.Cause(ctx))
+}
@@ -1,17 +1,51 @@
 package cli
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/harrybawsac/knmi-go/internal/config"
 	"github.com/harrybawsac/knmi-go/internal/db"
 	"github.com/harrybawsac/knmi-go/internal/fetch"
 	"github.com/harrybawsac/knmi-go/internal/parser"
+	"github.com/harrybawsac/knmi-go/internal/validate"
 	"github.com/spf13/cobra"
 )
 
 var dataURL string
 var dryRun bool
+var stationFlags []int
+var upsert bool
+var reviseDays int
+var resolution string
+var datasetName string
+var sinceYear int
+var retries int
+var timeout time.Duration
+var force bool
+var cacheDir string
+var fromCache bool
+var offline bool
+var inputPath string
+var pinnedSHA256 string
+var checksumSidecar bool
+var maxExtractedMB int64
+var entryPattern string
+var sourceName string
+var parseModeName string
+var rejectFile string
+var validationName string
+var ruleSeverities []string
 
 // newSyncCommand creates the sync subcommand.
 func newSyncCommand() *cobra.Command {
@@ -20,13 +54,104 @@ func newSyncCommand() *cobra.Command {
 		Short: "Download and sync KNMI weather data",
 		Long: `Download weather data from KNMI and sync to the database.
 
-The command downloads a zip file from the KNMI website, extracts the CSV data,
-and inserts new records into the database. Existing records are skipped.`,
+The command downloads a zip file per station from the KNMI website, extracts
+the CSV data, and inserts new records into the database. Existing records are
+skipped.
+
+Local files are synced with --input or a file:// URL. They may be zip
+archives or already extracted data files; the format is detected from the
+file content. Use --input - to read a single station's data from standard
+input, e.g. piped from curl or gunzip.
+
+Stations are selected with --station (repeatable) or KNMI_STATIONS
+(comma-separated). When syncing more than one station, the data URL must
+contain a {station} placeholder. A data URL or --input without {station}
+combined with --station must hold only that station's records; without
+--station it is synced for whichever stations it holds.
+
+KNMI revises recent values after quality control. Use --revise-days N to
+re-compare the last N days before each station's latest record and update
+changed rows, or --upsert to re-compare every record in the file.
+
+Use --resolution hourly to sync hourly observations instead of daily ones.
+Hourly archives are split per decade; every decade from the station's latest
+hourly record (or --since for stations without hourly data) up to the current
+one is fetched.
+
+Use --dataset precipitation to sync the daily files of KNMI's volunteer
+precipitation stations (neerslaggeg) instead of the automatic weather stations.
+
+Failed downloads (network errors and 5xx responses) are retried up to
+--retries times with exponential backoff. An interrupted download resumes
+where it stopped instead of starting over.
+
+Files that have not changed since the last sync (according to their ETag or
+Last-Modified header) are not downloaded again. Use --force to sync them anyway.
+
+With --cache-dir (or KNMI_CACHE_DIR) every downloaded archive is kept in a
+local cache. --from-cache (or --offline) syncs from the newest cached copy
+of each archive without network access, e.g. to reprocess the data after a
+schema change.
+
+Archives can be verified before they are synced: --sha256 pins the expected
+SHA-256 hash, and --checksum-sidecar checks each archive against the hash in
+"<url>.sha256". Corrupt or truncated archives are rejected, as are data files
+that extract to more than --max-extracted-mb. The hash of every synced file
+is recorded in the sync_runs table.
+
+Every .txt file in an archive is synced, so bulk archives holding the data of
+several stations are ingested in one run. Use --entry to select the data
+files with a glob pattern instead, e.g. --entry 'etmgeg_2*.txt'.
+
+Use --source opendata to fetch the archives from the KNMI Open Data API
+instead of the CDN. It requires an API key in KNMI_API_KEY; the files are
+looked up by name in the dataset set by KNMI_OPENDATA_DATASET and
+KNMI_OPENDATA_VERSION, so only daily weather data can be synced this way.
+--url also accepts Open Data URLs of the form
+opendata://<dataset>/<version>/<filename>, where the filename may be a glob
+pattern selecting the most recently modified match.
+
+Data lines are parsed in strict mode by default: a malformed line, such as
+one with an invalid date or a non-numeric measurement, fails the file with
+its line and column. With --parse-mode lenient such lines are skipped and
+the rest of the file is synced; the rejected lines are recorded in the
+parse_rejects table, or in the CSV file set by --reject-file.
+
+Daily weather records are checked for physically implausible values, such
+as TN > TX or a relative humidity above 100%, before they are stored. By
+default a record that fails a check is reported and stored anyway. Use
+--validation quarantine to hold such records back for review with
+'knmi quarantine', --validation reject to drop them, or --validation off to
+skip the checks. --rule-severity <rule>=<severity> sets the severity of a
+single rule, e.g. --rule-severity cloud-cover=off.
+The sync output ends with a summary of the checks that failed.`,
 		RunE: runSync,
 	}
 
-	cmd.Flags().StringVar(&dataURL, "url", "", "Override KNMI data URL")
+	cmd.Flags().StringVar(&dataURL, "url", "", "Override KNMI data URL (may contain {station}; file:// URLs read local files)")
+	cmd.Flags().StringVar(&sourceName, "source", fetch.DefaultSourceName, "Where to fetch archives from: cdn or opendata (KNMI Open Data API)")
+	cmd.Flags().StringVar(&parseModeName, "parse-mode", "strict", "How to handle malformed data lines: strict (fail) or lenient (skip and record)")
+	cmd.Flags().StringVar(&rejectFile, "reject-file", "", "Write lines rejected in lenient parse mode to this CSV file instead of the parse_rejects table")
+	cmd.Flags().StringVar(&validationName, "validation", "warn", "What to do with implausible daily records: warn, quarantine, reject or off")
+	cmd.Flags().StringSliceVar(&ruleSeverities, "rule-severity", nil, "Severity of a single validation rule as <rule>=<severity> (repeatable)")
+	cmd.Flags().StringVar(&inputPath, "input", "", "Sync a local zip archive or extracted data file instead of downloading (may contain {station}; - reads standard input)")
+	cmd.Flags().IntSliceVar(&stationFlags, "station", nil, "Station number to sync (repeatable, overrides KNMI_STATIONS)")
 	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Preview records without inserting")
+	cmd.Flags().BoolVar(&upsert, "upsert", false, "Update existing records whose values changed")
+	cmd.Flags().IntVar(&reviseDays, "revise-days", 0, "Re-compare the last N days per station and update changed records (implies --upsert)")
+	cmd.Flags().StringVar(&resolution, "resolution", "daily", "Data resolution to sync: daily or hourly")
+	cmd.Flags().StringVar(&datasetName, "dataset", "weather", "Dataset to sync: weather or precipitation")
+	cmd.Flags().IntVar(&sinceYear, "since", 0, "First year to fetch for stations without hourly data (default: current year)")
+	cmd.Flags().IntVar(&retries, "retries", fetch.DefaultRetries, "Number of times a failed download is retried")
+	cmd.Flags().BoolVar(&force, "force", false, "Download files even if they are unchanged since the last sync")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Keep downloaded archives in this directory (overrides KNMI_CACHE_DIR)")
+	cmd.Flags().BoolVar(&fromCache, "from-cache", false, "Sync from the newest cached archives instead of downloading")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Alias for --from-cache")
+	cmd.Flags().DurationVar(&timeout, "timeout", fetch.DefaultTimeout, "Timeout per download attempt (0 disables the timeout)")
+	cmd.Flags().StringVar(&pinnedSHA256, "sha256", "", "Expected SHA-256 hash of the archive")
+	cmd.Flags().BoolVar(&checksumSidecar, "checksum-sidecar", false, "Verify each archive against the hash in <url>.sha256")
+	cmd.Flags().StringVar(&entryPattern, "entry", fetch.DefaultDataFilePattern, "Glob pattern selecting the data files to sync in each archive")
+	cmd.Flags().Int64Var(&maxExtractedMB, "max-extracted-mb", fetch.DefaultMaxExtractedSize>>20, "Maximum size in MB of an extracted data file (0 disables the limit)")
 
 	return cmd
 }
@@ -40,6 +165,15 @@ func formatPreviewValue(v *int) string {
 	return fmt.Sprintf("%d", *v)
 }
 
+// formatPrecipitationValue formats a precipitation value for display,
+// showing "trace" for trace amounts.
+func formatPrecipitationValue(v *int, trace bool) string {
+	if trace {
+		return "trace"
+	}
+	return formatPreviewValue(v)
+}
+
 // printPreviewTable prints a tabular preview of weather records.
 // Handles empty result case per FR-008.
 func printPreviewTable(records []parser.WeatherRecord, total int) {
@@ -72,7 +206,7 @@ func printPreviewTable(records []parser.WeatherRecord, total int) {
 			formatPreviewValue(rec.TN),
 			formatPreviewValue(rec.TX),
 			formatPreviewValue(rec.FG),
-			formatPreviewValue(rec.RH),
+			formatPrecipitationValue(rec.RH, rec.RHTrace),
 		)
 	}
 
@@ -84,77 +218,282 @@ func printPreviewTable(records []parser.WeatherRecord, total int) {
 	}
 }
 
+// syncTarget is a single station and the URL its data is fetched from.
+type syncTarget struct {
+	// Station is 0 for a data file synced for whichever stations it
+	// holds, i.e. a URL without {station} synced without --station.
+	Station int
+	URL     string
+
+	// Fixed reports whether the station was requested with --station for a
+	// URL without {station}. The data file is then checked to hold only
+	// records of the station.
+	Fixed bool
+
+	// read collects the stations of the records read for a target
+	// without a station, so output can name them.
+	read map[int]bool
+}
+
+// label names the target in output: its station, or the stations read
+// from its data file if it has none. Before any record was read, a target
+// without a station is named by its URL.
+func (t syncTarget) label() string {
+	if t.Station != 0 {
+		return fmt.Sprintf("Station %d", t.Station)
+	}
+	if len(t.read) == 0 {
+		return t.URL
+	}
+
+	ids := make([]int, 0, len(t.read))
+	for id := range t.read {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = strconv.Itoa(id)
+	}
+	if len(ids) == 1 {
+		return "Station " + names[0]
+	}
+	return "Stations " + strings.Join(names, ", ")
+}
+
+// describe names the target in log messages: its station, or its URL if
+// it has none.
+func (t syncTarget) describe() string {
+	if t.Station != 0 {
+		return fmt.Sprintf("station %d", t.Station)
+	}
+	return t.URL
+}
+
+// checkStation returns an error if the target is fixed and a record of
+// batch belongs to another station than the one requested. For a target
+// without a station it notes the stations of the records instead.
+func checkStation[T any](target syncTarget, batch []T, station func(*T) int) error {
+	if target.read != nil {
+		for i := range batch {
+			target.read[station(&batch[i])] = true
+		}
+		return nil
+	}
+	if !target.Fixed {
+		return nil
+	}
+	for i := range batch {
+		if id := station(&batch[i]); id != target.Station {
+			return fmt.Errorf("data file holds records of station %d, not of the requested station %d", id, target.Station)
+		}
+	}
+	return nil
+}
+
+// weatherStation returns the station number of a daily weather record.
+func weatherStation(rec *parser.WeatherRecord) int {
+	return rec.StationID
+}
+
+// resolveSyncTargets determines which stations to sync and where to fetch
+// them, using urlTemplate unless --url or --input is given. With --source
+// the files named by urlTemplate are located in that source.
+//
+// A URL without {station} serves a single data file. Combined with
+// --station, the file must hold the records of that station; without it,
+// the file is synced for whichever stations it holds, as a single target
+// without a station.
+func resolveSyncTargets(cfg *config.Config, urlTemplate string) ([]syncTarget, error) {
+	stations, err := uniqueStations(stationFlags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --station: %w", err)
+	}
+
+	switch {
+	case inputPath != "" && dataURL != "":
+		return nil, fmt.Errorf("--input and --url cannot be combined")
+	case inputPath == stdinInput:
+		urlTemplate = stdinInput
+	case inputPath != "":
+		urlTemplate, err = fetch.FileURL(inputPath)
+		if err != nil {
+			return nil, err
+		}
+	case dataURL != "":
+		urlTemplate = dataURL
+	case sourceName != fetch.DefaultSourceName:
+		source, err := fetch.NewNamedSource(sourceName, sourceOptions())
+		if err != nil {
+			return nil, err
+		}
+		locator, ok := source.(fetch.Locator)
+		if !ok {
+			return nil, fmt.Errorf("source %q cannot serve KNMI data files by name; use --url", sourceName)
+		}
+		urlTemplate = locator.Locate(urlTemplate)
+	}
+
+	fixed := !config.HasStationPlaceholder(urlTemplate)
+	if fixed && len(stations) == 0 {
+		return []syncTarget{{URL: urlTemplate, read: make(map[int]bool)}}, nil
+	}
+	if len(stations) == 0 {
+		stations, err = cfg.StationList()
+		if err != nil {
+			return nil, fmt.Errorf("parsing KNMI_STATIONS: %w", err)
+		}
+	}
+
+	if inputPath == stdinInput && len(stations) > 1 {
+		return nil, fmt.Errorf("standard input holds the data of a single station")
+	}
+	if len(stations) > 1 && fixed {
+		return nil, fmt.Errorf("data URL must contain %s when syncing multiple stations", config.StationPlaceholder)
+	}
+
+	targets := make([]syncTarget, 0, len(stations))
+	for _, station := range stations {
+		targets = append(targets, syncTarget{
+			Station: station,
+			URL:     config.StationURL(urlTemplate, station),
+			Fixed:   fixed,
+		})
+	}
+
+	return targets, nil
+}
+
+// uniqueStations returns stations without duplicates, in their original
+// order. Station numbers must be positive, as in KNMI_STATIONS.
+func uniqueStations(stations []int) ([]int, error) {
+	seen := make(map[int]bool, len(stations))
+	unique := make([]int, 0, len(stations))
+	for _, station := range stations {
+		if station <= 0 {
+			return nil, fmt.Errorf("invalid station number %q", strconv.Itoa(station))
+		}
+		if !seen[station] {
+			seen[station] = true
+			unique = append(unique, station)
+		}
+	}
+	return unique, nil
+}
+
+// warnUnknownColumns reports data file columns that are not synced.
+func warnUnknownColumns(url string, columns []string) {
+	if len(columns) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring unknown columns in %s: %s\n", url, strings.Join(columns, ", "))
+	}
+}
+
 // runSync executes the sync command.
 func runSync(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	cfg := GetConfig()
 	dbURL := cfg.DatabaseURL
 	if databaseURL != "" {
 		dbURL = databaseURL
 	}
 
-	// Determine data URL
-	url := dataURL
-	if url == "" {
-		url = cfg.KNMIDataURL
+	if reviseDays < 0 {
+		return fmt.Errorf("--revise-days must not be negative")
 	}
-
-	// Download data
-	LogVerbose("Downloading from %s...", url)
-	zipData, err := fetch.Download(url)
-	if err != nil {
-		return fmt.Errorf("failed to download data: %w", err)
+	if retries < 0 {
+		return fmt.Errorf("--retries must not be negative")
 	}
-	LogVerbose("Downloaded %.2f MB", float64(len(zipData))/(1024*1024))
-
-	// Extract zip
-	LogVerbose("Extracting archive...")
-	csvData, err := fetch.ExtractZip(zipData)
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+	if isOffline() && cacheDirectory(cfg) == "" {
+		return fmt.Errorf("--from-cache requires --cache-dir or KNMI_CACHE_DIR")
+	}
+	if pinnedSHA256 != "" && !isSHA256(pinnedSHA256) {
+		return fmt.Errorf("--sha256 must be a hex-encoded SHA-256 hash (64 characters)")
+	}
+	if pinnedSHA256 != "" && checksumSidecar {
+		return fmt.Errorf("--sha256 and --checksum-sidecar cannot be combined")
+	}
+	if checksumSidecar && inputPath == stdinInput {
+		return fmt.Errorf("--checksum-sidecar cannot be used with standard input")
+	}
+	if maxExtractedMB < 0 {
+		return fmt.Errorf("--max-extracted-mb must not be negative")
+	}
+	if _, err := path.Match(entryPattern, ""); err != nil {
+		return fmt.Errorf("invalid --entry pattern %q: %w", entryPattern, err)
+	}
+	mode, err := parser.ParseModeFromName(parseModeName)
 	if err != nil {
-		return fmt.Errorf("failed to extract zip: %w", err)
+		return fmt.Errorf("invalid --parse-mode: %w", err)
 	}
-
-	// Parse CSV
-	LogVerbose("Parsing CSV...")
-	records, err := parser.ParseCSV(bytes.NewReader(csvData))
+	if rejectFile != "" && mode != parser.ModeLenient {
+		return fmt.Errorf("--reject-file requires --parse-mode lenient")
+	}
+	validator, err := newValidator()
 	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %w", err)
+		return err
 	}
-	LogVerbose("Parsed %d rows", len(records))
 
-	// Dry-run mode: preview without inserting
-	if dryRun {
-		// If database is configured, filter to show only new records
-		if dbURL != "" {
-			LogVerbose("Connecting to database for duplicate filtering...")
-			database, err := db.Connect(dbURL)
-			if err != nil {
-				LogVerbose("Warning: could not connect to database, showing all parsed records")
-				printPreviewTable(records, len(records))
-				return nil
-			}
-			defer database.Close()
+	if sourceName != fetch.DefaultSourceName {
+		if inputPath != "" {
+			return fmt.Errorf("--source and --input cannot be combined")
+		}
+		if dataURL != "" {
+			return fmt.Errorf("--source and --url cannot be combined; the scheme of the URL selects its source")
+		}
+		// KNMI_OPENDATA_DATASET names a single dataset, which cannot
+		// also hold the hourly and precipitation files
+		if datasetName != "weather" || resolution != "daily" {
+			return fmt.Errorf("--source %s only serves daily weather data; use an %s:// --url for other files", sourceName, fetch.OpenDataScheme)
+		}
+		if _, err := fetch.NewNamedSource(sourceName, sourceOptions()); err != nil {
+			return fmt.Errorf("invalid --source: %w", err)
+		}
+	}
 
-			repo := db.NewWeatherRepository(database)
-			tableExists, err := repo.TableExists()
-			if err != nil || !tableExists {
-				LogVerbose("Warning: table not found, showing all parsed records")
-				printPreviewTable(records, len(records))
-				return nil
-			}
+	switch datasetName {
+	case "weather":
+	case "precipitation":
+		if resolution != "daily" {
+			return fmt.Errorf("precipitation data is only available at daily resolution")
+		}
+		if isUpsertMode() {
+			return fmt.Errorf("--upsert and --revise-days are only supported for weather data")
+		}
+		targets, err := resolveSyncTargets(cfg, cfg.KNMIPrecipitationURL)
+		if err != nil {
+			return err
+		}
+		return runDatasetSync(ctx, precipitationDataset, dbURL, targets)
+	default:
+		return fmt.Errorf("invalid dataset %q (expected weather or precipitation)", datasetName)
+	}
 
-			LogVerbose("Dry-run mode: filtering new records...")
-			newRecords, err := repo.FilterNewRecords(records)
-			if err != nil {
-				return fmt.Errorf("filtering new records: %w", err)
-			}
-			printPreviewTable(newRecords, len(newRecords))
-			return nil
+	switch resolution {
+	case "daily":
+	case "hourly":
+		if isUpsertMode() {
+			return fmt.Errorf("--upsert and --revise-days are only supported for daily data")
 		}
+		targets, err := resolveSyncTargets(cfg, cfg.KNMIHourlyURL)
+		if err != nil {
+			return err
+		}
+		return runDatasetSync(ctx, hourlyDataset, dbURL, targets)
+	default:
+		return fmt.Errorf("invalid resolution %q (expected daily or hourly)", resolution)
+	}
 
-		// No database configured - just show parsed records
-		LogVerbose("Dry-run mode: no database configured, showing parsed records")
-		printPreviewTable(records, len(records))
-		return nil
+	targets, err := resolveSyncTargets(cfg, cfg.KNMIDataURL)
+	if err != nil {
+		return err
+	}
+
+	// Dry-run mode: preview without inserting
+	if dryRun {
+		return runDryRun(ctx, dbURL, targets, validator)
 	}
 
 	// Normal sync mode - database is required
@@ -164,7 +503,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 	// Connect to database
 	LogVerbose("Connecting to database...")
-	database, err := db.Connect(dbURL)
+	database, err := db.Connect(ctx, dbURL)
 	if err != nil {
 		return fmt.Errorf("connecting to database: %w", err)
 	}
@@ -172,7 +511,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 	// Check if migrations have been applied
 	repo := db.NewWeatherRepository(database)
-	tableExists, err := repo.TableExists()
+	tableExists, err := repo.TableExists(ctx)
 	if err != nil {
 		return fmt.Errorf("checking database state: %w", err)
 	}
@@ -180,39 +519,285 @@ func runSync(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no migrations applied. Run 'knmi migrate' first")
 	}
 
-	// Filter to only new records based on latest date in DB
-	latestDate, err := repo.GetLatestDate()
+	stations, err := stationRepository(ctx, database)
 	if err != nil {
-		return fmt.Errorf("getting latest date: %w", err)
-	}
-	if latestDate != nil {
-		LogVerbose("Latest record in DB: %s", latestDate.Format("2006-01-02"))
-		var newRecords []parser.WeatherRecord
-		for _, rec := range records {
-			if rec.Date.After(*latestDate) {
-				newRecords = append(newRecords, rec)
-			}
-		}
-		LogVerbose("Filtered %d records to %d new records", len(records), len(newRecords))
-		records = newRecords
+		return err
+	}
+	tracker, err := newSourceTracker(ctx, database, "weather_records")
+	if err != nil {
+		return err
 	}
+	rejects, err := newRejectReport(ctx, database, "weather_records")
+	if err != nil {
+		return err
+	}
+	defer rejects.close()
+	validation, err := newValidationReport(ctx, database, validator)
+	if err != nil {
+		return err
+	}
+	defer validation.summary()
 
-	// Insert records
-	LogVerbose("Inserting records...")
-	result, err := repo.InsertRecords(records)
+	// Look up the latest date per station so each one is filtered against
+	// its own history rather than the newest record of any station
+	latestDates, err := repo.GetLatestDates(ctx)
 	if err != nil {
-		return fmt.Errorf("database error: %w", err)
+		return fmt.Errorf("getting latest dates: %w", err)
+	}
+
+	// Sync each station, continuing past failures so one bad station
+	// does not block the rest of the run. An interruption ends the run.
+	inserted := 0
+	updated := 0
+	failed := 0
+	for i, target := range targets {
+		result, err := syncStation(ctx, repo, stations, tracker, rejects, validation, target, latestDates)
+		if ctx.Err() != nil {
+			if result != nil {
+				inserted += result.Inserted
+				updated += result.Updated
+			}
+			fmt.Printf("%s: interrupted\n", target.label())
+			return interrupted(ctx, i, len(targets), inserted, updated, "records")
+		}
+		if errors.Is(err, fetch.ErrNotModified) {
+			fmt.Printf("%s: source unchanged\n", target.label())
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%s: failed: %v\n", target.label(), err)
+			failed++
+			continue
+		}
+		if isUpsertMode() {
+			fmt.Printf("%s: %d new records, %d updated\n", target.label(), result.Inserted, result.Updated)
+		} else {
+			fmt.Printf("%s: %d new records\n", target.label(), result.Inserted)
+		}
+		inserted += result.Inserted
+		updated += result.Updated
 	}
 
 	// Get total count
-	total, err := repo.GetTotalCount()
+	total, err := repo.GetTotalCount(ctx)
 	if err != nil {
 		LogVerbose("Warning: could not get total count: %v", err)
-		total = result.Inserted
+		total = inserted
 	}
 
 	// Print summary
-	fmt.Printf("Synced %d new records (%d total)\n", result.Inserted, total)
+	if isUpsertMode() {
+		fmt.Printf("Synced %d new records, updated %d (%d total)\n", inserted, updated, total)
+	} else {
+		fmt.Printf("Synced %d new records (%d total)\n", inserted, total)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stations failed to sync", failed, len(targets))
+	}
+
+	return nil
+}
+
+// syncStation streams one station's data file and inserts its new records
+// batch by batch. Records are checked by validation before they are stored.
+func syncStation(ctx context.Context, repo *db.WeatherRepository, stations *db.StationRepository, tracker *sourceTracker, rejects *rejectReport, validation *validationReport, target syncTarget, latestDates map[int]time.Time) (*db.InsertResult, error) {
+	LogVerbose("Syncing %s...", target.describe())
+	if latest, ok := latestDates[target.Station]; ok {
+		LogVerbose("Latest record in DB for station %d: %s", target.Station, latest.Format("2006-01-02"))
+	}
+
+	// Filter to only new records based on each station's latest date in DB.
+	// With --revise-days the cutoff moves back so recent records are
+	// re-compared; plain --upsert re-compares the whole file.
+	var cutoffs map[int]time.Time
+	switch {
+	case reviseDays > 0:
+		cutoffs = shiftDates(latestDates, -reviseDays)
+	case !upsert:
+		cutoffs = latestDates
+	}
+
+	// Station metadata is stored once per data file of the archive
+	var metadataSynced *parser.Reader[parser.WeatherRecord]
+	return streamRecords(ctx, target.URL, target.Station, tracker, rejects, parser.NewReader, func(reader *parser.Reader[parser.WeatherRecord], batch []parser.WeatherRecord) (*db.InsertResult, error) {
+		// Keep station metadata up to date and make sure every station
+		// the records reference is registered
+		if reader != metadataSynced {
+			if err := syncStationMetadata(ctx, stations, reader.Stations()); err != nil {
+				return nil, err
+			}
+			metadataSynced = reader
+		}
+		if err := checkStation(target, batch, weatherStation); err != nil {
+			return nil, err
+		}
+		if err := stations.EnsureStations(ctx, recordStationIDs(batch)); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+
+		filtered := db.FilterAfterLatest(batch, cutoffs)
+		LogVerbose("Filtered %d records to %d records", len(batch), len(filtered))
+		filtered, quarantined := validation.check(filtered)
+		if len(filtered) == 0 && len(quarantined) == 0 {
+			return &db.InsertResult{}, nil
+		}
+
+		// Quarantined and accepted records of the batch are committed
+		// together, so an interrupted batch leaves neither behind
+		tx, err := repo.BeginTx(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := validation.store(ctx, tx, filtered, quarantined); err != nil {
+			return nil, err
+		}
+
+		var result *db.InsertResult
+		if isUpsertMode() {
+			LogVerbose("Upserting records...")
+			result, err = repo.UpsertRecordsTx(ctx, tx, filtered)
+		} else {
+			LogVerbose("Inserting records...")
+			result, err = repo.InsertRecordsTx(ctx, tx, filtered)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("database error: committing transaction: %w", err)
+		}
+		return result, nil
+	})
+}
+
+// stationRepository returns the repository for station metadata, checking
+// that its table has been created.
+func stationRepository(ctx context.Context, database *sql.DB) (*db.StationRepository, error) {
+	stations := db.NewStationRepository(database)
+	tableExists, err := stations.TableExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking database state: %w", err)
+	}
+	if !tableExists {
+		return nil, fmt.Errorf("stations table not found. Run 'knmi migrate' first")
+	}
+	return stations, nil
+}
+
+// syncStationMetadata stores the station metadata found in a data file.
+func syncStationMetadata(ctx context.Context, stations *db.StationRepository, metadata []parser.Station) error {
+	for _, st := range metadata {
+		LogVerbose("Station %d: %s (%.3f, %.3f)", st.ID, st.Name, st.Latitude, st.Longitude)
+	}
+	if err := stations.UpsertStations(ctx, metadata); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+// recordStationIDs returns the distinct station numbers of records.
+func recordStationIDs(records []parser.WeatherRecord) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, rec := range records {
+		if !seen[rec.StationID] {
+			seen[rec.StationID] = true
+			ids = append(ids, rec.StationID)
+		}
+	}
+	return ids
+}
+
+// isUpsertMode reports whether existing records should be re-compared and updated.
+func isUpsertMode() bool {
+	return upsert || reviseDays > 0
+}
+
+// isSHA256 reports whether s is a hex-encoded SHA-256 hash.
+func isSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+// shiftDates returns a copy of dates with every date moved by the given number of days.
+func shiftDates(dates map[int]time.Time, days int) map[int]time.Time {
+	shifted := make(map[int]time.Time, len(dates))
+	for station, date := range dates {
+		shifted[station] = date.AddDate(0, 0, days)
+	}
+	return shifted
+}
+
+// runDryRun previews the records each station would insert without writing.
+// Records held back or dropped by validation are left out of the preview.
+func runDryRun(ctx context.Context, dbURL string, targets []syncTarget, validator *validate.Validator) error {
+	// If database is configured, filter to show only new records
+	var repo *db.WeatherRepository
+	if dbURL != "" {
+		LogVerbose("Connecting to database for duplicate filtering...")
+		database, err := db.Connect(ctx, dbURL)
+		if err != nil {
+			LogVerbose("Warning: could not connect to database, showing all parsed records")
+		} else {
+			defer database.Close()
+
+			repo = db.NewWeatherRepository(database)
+			tableExists, err := repo.TableExists(ctx)
+			if err != nil || !tableExists {
+				LogVerbose("Warning: table not found, showing all parsed records")
+				repo = nil
+			}
+		}
+	} else {
+		LogVerbose("Dry-run mode: no database configured, showing parsed records")
+	}
+
+	rejects, err := newRejectReport(ctx, nil, "weather_records")
+	if err != nil {
+		return err
+	}
+	defer rejects.close()
+	validation, err := newValidationReport(ctx, nil, validator)
+	if err != nil {
+		return err
+	}
+	defer validation.summary()
+
+	for i, target := range targets {
+		var p preview[parser.WeatherRecord]
+		_, err := streamRecords(ctx, target.URL, target.Station, nil, rejects, parser.NewReader, func(_ *parser.Reader[parser.WeatherRecord], batch []parser.WeatherRecord) (*db.InsertResult, error) {
+			if err := checkStation(target, batch, weatherStation); err != nil {
+				return nil, err
+			}
+			records := batch
+			if repo != nil {
+				LogVerbose("Dry-run mode: filtering new records...")
+				var err error
+				records, err = repo.FilterNewRecords(ctx, batch)
+				if err != nil {
+					return nil, fmt.Errorf("filtering new records: %w", err)
+				}
+			}
+			records, _ = validation.check(records)
+			p.add(records)
+			return nil, nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", target.describe(), err)
+		}
+
+		if len(targets) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", target.label())
+		}
+		printPreviewTable(p.records, p.total)
+	}
 
 	return nil
 }
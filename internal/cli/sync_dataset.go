@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// dataset describes a kind of KNMI data that is synced by fetching archives,
+// parsing them and inserting the records after each station's latest one.
+type dataset[T any] struct {
+	// name describes the records in output, e.g. "hourly records".
+	name string
+
+	// table is the database table the records are stored in.
+	table string
+
+	// urls returns the archive URLs to fetch for a station.
+	urls func(target syncTarget, latest map[int]time.Time) []string
+
+	// reader streams the records of a data file.
+	reader func(r io.Reader) *parser.Reader[T]
+
+	// filter drops records at or before the latest synced time of their station.
+	filter func(records []T, latest map[int]time.Time) []T
+
+	// store binds the dataset's repository to a database connection.
+	store func(database *sql.DB) recordStore[T]
+
+	// printRows prints a dry-run table of records, including a header line.
+	printRows func(records []T)
+
+	// stations reports whether the files describe automatic weather
+	// stations whose metadata is kept in the stations table.
+	stations bool
+
+	// station returns the station number of a record.
+	station func(rec *T) int
+}
+
+// recordStore is the repository a dataset's records are written to.
+type recordStore[T any] struct {
+	latest func(ctx context.Context) (map[int]time.Time, error)
+	insert func(ctx context.Context, records []T) (*db.InsertResult, error)
+	count  func(ctx context.Context) (int, error)
+}
+
+// stationURLs returns the target's URL as the only archive to fetch.
+func stationURLs(target syncTarget, latest map[int]time.Time) []string {
+	return []string{target.URL}
+}
+
+// runDatasetSync syncs a dataset for each target station.
+func runDatasetSync[T any](ctx context.Context, ds *dataset[T], dbURL string, targets []syncTarget) error {
+	if dryRun {
+		return runDatasetDryRun(ctx, ds, dbURL, targets)
+	}
+
+	if dbURL == "" {
+		return fmt.Errorf("database URL not configured (set DATABASE_URL or use --database-url)")
+	}
+
+	LogVerbose("Connecting to database...")
+	database, err := db.Connect(ctx, dbURL)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer database.Close()
+
+	tableExists, err := db.TableExists(ctx, database, ds.table)
+	if err != nil {
+		return fmt.Errorf("checking database state: %w", err)
+	}
+	if !tableExists {
+		return fmt.Errorf("%s table not found. Run 'knmi migrate' first", ds.table)
+	}
+
+	var stations *db.StationRepository
+	if ds.stations {
+		stations, err = stationRepository(ctx, database)
+		if err != nil {
+			return err
+		}
+	}
+
+	tracker, err := newSourceTracker(ctx, database, ds.table)
+	if err != nil {
+		return err
+	}
+	rejects, err := newRejectReport(ctx, database, ds.table)
+	if err != nil {
+		return err
+	}
+	defer rejects.close()
+
+	store := ds.store(database)
+	latest, err := store.latest(ctx)
+	if err != nil {
+		return fmt.Errorf("getting latest records: %w", err)
+	}
+
+	inserted := 0
+	failed := 0
+	for i, target := range targets {
+		count, err := syncDatasetStation(ctx, ds, store, stations, tracker, rejects, target, latest)
+		if ctx.Err() != nil {
+			fmt.Printf("%s: interrupted\n", target.label())
+			return interrupted(ctx, i, len(targets), inserted+count, 0, ds.name)
+		}
+		if errors.Is(err, fetch.ErrNotModified) {
+			fmt.Printf("%s: source unchanged\n", target.label())
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%s: failed: %v\n", target.label(), err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %d new %s\n", target.label(), count, ds.name)
+		inserted += count
+	}
+
+	total, err := store.count(ctx)
+	if err != nil {
+		LogVerbose("Warning: could not get total count: %v", err)
+		total = inserted
+	}
+
+	fmt.Printf("Synced %d new %s (%d total)\n", inserted, ds.name, total)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stations failed to sync", failed, len(targets))
+	}
+
+	return nil
+}
+
+// syncDatasetStation fetches every archive a station still needs and
+// inserts the new records, returning the number inserted. Station metadata
+// is stored when stations is not nil. Archives unchanged since the last sync
+// are skipped; if all are, the error wraps fetch.ErrNotModified. On error
+// the count includes the records inserted before it.
+func syncDatasetStation[T any](ctx context.Context, ds *dataset[T], store recordStore[T], stations *db.StationRepository, tracker *sourceTracker, rejects *rejectReport, target syncTarget, latest map[int]time.Time) (int, error) {
+	LogVerbose("Syncing %s for %s...", ds.name, target.describe())
+
+	urls := ds.urls(target, latest)
+	inserted := 0
+	unchanged := 0
+	for _, url := range urls {
+		var metadataSynced *parser.Reader[T]
+		result, err := streamRecords(ctx, url, target.Station, tracker, rejects, ds.reader, func(reader *parser.Reader[T], batch []T) (*db.InsertResult, error) {
+			if stations != nil && reader != metadataSynced {
+				if err := syncStationMetadata(ctx, stations, reader.Stations()); err != nil {
+					return nil, err
+				}
+				metadataSynced = reader
+			}
+
+			if err := checkStation(target, batch, ds.station); err != nil {
+				return nil, err
+			}
+
+			newRecords := ds.filter(batch, latest)
+			LogVerbose("Filtered %d records to %d new records", len(batch), len(newRecords))
+
+			result, err := store.insert(ctx, newRecords)
+			if err != nil {
+				return nil, fmt.Errorf("database error: %w", err)
+			}
+			return result, nil
+		})
+		if errors.Is(err, fetch.ErrNotModified) {
+			LogVerbose("%s unchanged since the last sync", url)
+			unchanged++
+			continue
+		}
+		if result != nil {
+			inserted += result.Inserted
+		}
+		if err != nil {
+			return inserted, err
+		}
+	}
+
+	if len(urls) > 0 && unchanged == len(urls) {
+		return 0, fmt.Errorf("all archives unchanged: %w", fetch.ErrNotModified)
+	}
+
+	return inserted, nil
+}
+
+// runDatasetDryRun previews the records each station would insert without writing.
+func runDatasetDryRun[T any](ctx context.Context, ds *dataset[T], dbURL string, targets []syncTarget) error {
+	latest := map[int]time.Time{}
+	if dbURL != "" {
+		LogVerbose("Connecting to database for duplicate filtering...")
+		database, err := db.Connect(ctx, dbURL)
+		if err != nil {
+			LogVerbose("Warning: could not connect to database, showing all parsed records")
+		} else {
+			defer database.Close()
+
+			tableExists, err := db.TableExists(ctx, database, ds.table)
+			if err == nil && tableExists {
+				latest, err = ds.store(database).latest(ctx)
+				if err != nil {
+					return fmt.Errorf("getting latest records: %w", err)
+				}
+			} else {
+				LogVerbose("Warning: table not found, showing all parsed records")
+			}
+		}
+	}
+
+	rejects, err := newRejectReport(ctx, nil, ds.table)
+	if err != nil {
+		return err
+	}
+	defer rejects.close()
+
+	for i, target := range targets {
+		var p preview[T]
+		for _, url := range ds.urls(target, latest) {
+			_, err := streamRecords(ctx, url, target.Station, nil, rejects, ds.reader, func(_ *parser.Reader[T], batch []T) (*db.InsertResult, error) {
+				if err := checkStation(target, batch, ds.station); err != nil {
+					return nil, err
+				}
+				p.add(ds.filter(batch, latest))
+				return nil, nil
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", target.describe(), err)
+			}
+		}
+
+		if len(targets) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", target.label())
+		}
+		printDatasetPreview(ds, p.records, p.total)
+	}
+
+	return nil
+}
+
+// printDatasetPreview prints a tabular preview of the last records out of
+// total records that would be inserted.
+func printDatasetPreview[T any](ds *dataset[T], records []T, total int) {
+	if total == 0 {
+		fmt.Printf("Dry-run mode: no new %s to insert\n", ds.name)
+		return
+	}
+
+	fmt.Printf("Dry-run mode: previewing %s that would be inserted\n", ds.name)
+	fmt.Println()
+
+	ds.printRows(records)
+
+	fmt.Println()
+	if total <= previewSize {
+		fmt.Printf("Total: %d new %s would be inserted (showing all)\n", total, ds.name)
+	} else {
+		fmt.Printf("Total: %d new %s would be inserted (showing last %d)\n", total, ds.name, previewSize)
+	}
+}
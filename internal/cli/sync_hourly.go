@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/config"
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// hourlyDataset syncs hourly observations (uurgeg files) into hourly_records.
+var hourlyDataset = &dataset[parser.HourlyRecord]{
+	name:   "hourly records",
+	table:  "hourly_records",
+	urls:   hourlyURLs,
+	reader: parser.NewHourlyReader,
+	filter: db.FilterHourlyAfterLatest,
+	store: func(database *sql.DB) recordStore[parser.HourlyRecord] {
+		repo := db.NewHourlyRepository(database)
+		return recordStore[parser.HourlyRecord]{
+			latest: repo.GetLatestTimes,
+			insert: repo.InsertRecords,
+			count:  repo.GetTotalCount,
+		}
+	},
+	printRows: printHourlyRows,
+	stations:  true,
+	station:   func(rec *parser.HourlyRecord) int { return rec.StationID },
+}
+
+// hourlyURLs returns the decade archive URLs to fetch for a station: from
+// the decade of its latest hourly record, or of --since (default: this
+// year) when it has none, up to the current decade.
+func hourlyURLs(target syncTarget, latestTimes map[int]time.Time) []string {
+	if !config.HasDecadePlaceholder(target.URL) {
+		return []string{target.URL}
+	}
+
+	now := time.Now()
+	from := now.Year()
+	if latest, ok := latestTimes[target.Station]; ok {
+		from = latest.Year()
+	} else if sinceYear > 0 {
+		from = sinceYear
+	}
+
+	var urls []string
+	for _, decade := range config.Decades(from, now.Year()) {
+		urls = append(urls, config.DecadeURL(target.URL, decade))
+	}
+	return urls
+}
+
+// printHourlyRows prints a table of hourly records.
+func printHourlyRows(records []parser.HourlyRecord) {
+	fmt.Printf("%-10s %3s %10s %6s %6s %6s %6s\n", "DATE", "HH", "STATION_ID", "T", "TD", "FH", "RH")
+	for _, rec := range records {
+		fmt.Printf("%-10s %3d %10d %6s %6s %6s %6s\n",
+			rec.Date.Format("2006-01-02"),
+			rec.Hour,
+			rec.StationID,
+			formatPreviewValue(rec.T),
+			formatPreviewValue(rec.TD),
+			formatPreviewValue(rec.FH),
+			formatPrecipitationValue(rec.RH, rec.RHTrace),
+		)
+	}
+}
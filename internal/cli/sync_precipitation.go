@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// precipitationDataset syncs volunteer precipitation station data
+// (neerslaggeg files) into precipitation_records.
+var precipitationDataset = &dataset[parser.PrecipitationRecord]{
+	name:   "precipitation records",
+	table:  "precipitation_records",
+	urls:   stationURLs,
+	reader: parser.NewPrecipitationReader,
+	filter: db.FilterPrecipitationAfterLatest,
+	store: func(database *sql.DB) recordStore[parser.PrecipitationRecord] {
+		repo := db.NewPrecipitationRepository(database)
+		return recordStore[parser.PrecipitationRecord]{
+			latest: repo.GetLatestDates,
+			insert: repo.InsertRecords,
+			count:  repo.GetTotalCount,
+		}
+	},
+	printRows: printPrecipitationRows,
+	station:   func(rec *parser.PrecipitationRecord) int { return rec.StationID },
+}
+
+// printPrecipitationRows prints a table of precipitation records.
+func printPrecipitationRows(records []parser.PrecipitationRecord) {
+	fmt.Printf("%-10s %10s %6s %6s\n", "DATE", "STATION_ID", "RD", "SX")
+	for _, rec := range records {
+		fmt.Printf("%-10s %10d %6s %6s\n",
+			rec.Date.Format("2006-01-02"),
+			rec.StationID,
+			formatPreviewValue(rec.RD),
+			formatPreviewValue(rec.SX),
+		)
+	}
+}
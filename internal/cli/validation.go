@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+	"github.com/harrybawsac/knmi-go/internal/validate"
+)
+
+// newValidator creates the validator for daily weather records from the
+// --validation and --rule-severity flags.
+func newValidator() (*validate.Validator, error) {
+	severity, err := validate.SeverityFromName(validationName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --validation: %w", err)
+	}
+
+	overrides := make(map[string]validate.Severity, len(ruleSeverities))
+	for _, flag := range ruleSeverities {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --rule-severity %q (expected <rule>=<severity>)", flag)
+		}
+		s, err := validate.SeverityFromName(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --rule-severity %q: %w", flag, err)
+		}
+		overrides[strings.TrimSpace(name)] = s
+	}
+
+	v, err := validate.New(severity, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --rule-severity: %w", err)
+	}
+	return v, nil
+}
+
+// validationReport applies the plausibility rules to the records of a sync,
+// stores the quarantined ones and counts the violations for the summary.
+type validationReport struct {
+	validator   *validate.Validator
+	quarantine  *db.QuarantineRepository
+	rules       map[string]int
+	warned      int
+	quarantined int
+	rejected    int
+}
+
+// newValidationReport creates a report checking records with validator.
+// Quarantined records are stored in the database; if database is nil, as
+// in a dry run, they are only counted.
+func newValidationReport(ctx context.Context, database *sql.DB, validator *validate.Validator) (*validationReport, error) {
+	report := &validationReport{validator: validator, rules: make(map[string]int)}
+	if database == nil {
+		return report, nil
+	}
+
+	// Without the table no record can have been quarantined before, so it
+	// is only required if a rule can quarantine
+	quarantine := db.NewQuarantineRepository(database)
+	tableExists, err := quarantine.TableExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking database state: %w", err)
+	}
+	if tableExists {
+		report.quarantine = quarantine
+	} else if validator.Uses(validate.SeverityQuarantine) {
+		return nil, fmt.Errorf("weather_records_quarantine table not found. Run 'knmi migrate' first")
+	}
+	return report, nil
+}
+
+// check validates a batch of records, returning the ones to store and the
+// ones to quarantine. Every flagged record is logged in verbose mode.
+func (r *validationReport) check(records []parser.WeatherRecord) ([]parser.WeatherRecord, []db.QuarantinedRecord) {
+	result := r.validator.Validate(records)
+
+	r.warned += len(result.Warned)
+	r.quarantined += len(result.Quarantined)
+	r.rejected += len(result.Rejected)
+	for _, group := range [][]validate.Flagged{result.Warned, result.Quarantined, result.Rejected} {
+		for _, flagged := range group {
+			r.log(&flagged)
+		}
+	}
+
+	now := time.Now()
+	quarantined := make([]db.QuarantinedRecord, len(result.Quarantined))
+	for i, flagged := range result.Quarantined {
+		quarantined[i] = db.QuarantinedRecord{Record: flagged.Record, QuarantinedAt: now}
+		for _, v := range flagged.Violations {
+			quarantined[i].Rules = append(quarantined[i].Rules, v.Rule)
+			quarantined[i].Messages = append(quarantined[i].Messages, v.Message)
+		}
+	}
+
+	return result.Accepted, quarantined
+}
+
+// store writes the quarantined records of a batch within tx, the
+// transaction the accepted records are stored in, and deletes the
+// quarantined copies of accepted records left by an earlier sync.
+func (r *validationReport) store(ctx context.Context, tx *sql.Tx, accepted []parser.WeatherRecord, quarantined []db.QuarantinedRecord) error {
+	if r.quarantine == nil {
+		return nil
+	}
+	if err := r.quarantine.ClearTx(ctx, tx, accepted); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if err := r.quarantine.QuarantineTx(ctx, tx, quarantined); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+// log records the violations of a flagged record.
+func (r *validationReport) log(flagged *validate.Flagged) {
+	violations := make([]string, len(flagged.Violations))
+	for i, v := range flagged.Violations {
+		r.rules[v.Rule]++
+		violations[i] = v.String()
+	}
+	LogVerbose("Station %d, %s: %s: %s",
+		flagged.Record.StationID,
+		flagged.Record.Date.Format("2006-01-02"),
+		flagged.Severity(),
+		strings.Join(violations, "; "),
+	)
+}
+
+// flagged returns the number of records that broke at least one rule.
+func (r *validationReport) flagged() int {
+	return r.warned + r.quarantined + r.rejected
+}
+
+// summary prints how many records broke the rules, and how often each rule
+// fired. Nothing is printed if every record passed.
+func (r *validationReport) summary() {
+	if r.flagged() == 0 {
+		return
+	}
+
+	fmt.Printf("Validation: %d records failed plausibility checks (%d warned, %d quarantined, %d rejected)\n",
+		r.flagged(), r.warned, r.quarantined, r.rejected)
+
+	names := make([]string, 0, len(r.rules))
+	for name := range r.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-20s %d\n", name, r.rules[name])
+	}
+	if r.quarantine != nil && r.quarantined > 0 {
+		fmt.Println("Review quarantined records with 'knmi quarantine list'")
+	}
+}
@@ -2,15 +2,47 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 const (
-	// DefaultKNMIDataURL is the default URL for KNMI weather data.
-	DefaultKNMIDataURL = "https://cdn.knmi.nl/knmi/map/page/klimatologie/gegevens/daggegevens/etmgeg_260.zip"
+	// DefaultKNMIDataURL is the default URL template for KNMI weather data.
+	// The {station} placeholder is replaced with the station number.
+	DefaultKNMIDataURL = "https://cdn.knmi.nl/knmi/map/page/klimatologie/gegevens/daggegevens/etmgeg_{station}.zip"
+
+	// DefaultKNMIHourlyURL is the default URL template for KNMI hourly weather data.
+	// Hourly archives are split per decade; the {decade} placeholder is
+	// replaced with a range such as "2021-2030".
+	DefaultKNMIHourlyURL = "https://cdn.knmi.nl/knmi/map/page/klimatologie/gegevens/uurgegevens/uurgeg_{station}_{decade}.zip"
+
+	// DefaultKNMIPrecipitationURL is the default URL template for KNMI
+	// precipitation station data.
+	DefaultKNMIPrecipitationURL = "https://cdn.knmi.nl/knmi/map/page/klimatologie/gegevens/monv_reeksen/neerslaggeg_{station}.zip"
+
+	// DefaultOpenDataURL is the base URL of the KNMI Open Data API.
+	DefaultOpenDataURL = "https://api.dataplatform.knmi.nl/open-data/v1"
+
+	// DefaultOpenDataDataset is the Open Data API dataset synced with
+	// --source opendata.
+	DefaultOpenDataDataset = "etmaalgegevensKNMIstations"
+
+	// DefaultOpenDataVersion is the version of the Open Data API dataset.
+	DefaultOpenDataVersion = "1"
 
 	// DefaultMigrationsDir is the default directory for SQL migration files.
 	DefaultMigrationsDir = "./migrations"
+
+	// DefaultStation is the station synced when none are configured (De Bilt).
+	DefaultStation = 260
+
+	// StationPlaceholder is replaced with the station number in data URLs.
+	StationPlaceholder = "{station}"
+
+	// DecadePlaceholder is replaced with the decade range in hourly data URLs.
+	DecadePlaceholder = "{decade}"
 )
 
 // Config holds the application configuration.
@@ -19,11 +51,38 @@ type Config struct {
 	DatabaseURL string
 
 	// KNMIDataURL is the URL to fetch KNMI weather data from.
+	// May contain a {station} placeholder.
 	KNMIDataURL string
 
+	// KNMIHourlyURL is the URL to fetch KNMI hourly weather data from.
+	// May contain {station} and {decade} placeholders.
+	KNMIHourlyURL string
+
+	// KNMIPrecipitationURL is the URL to fetch KNMI precipitation station data from.
+	// May contain a {station} placeholder.
+	KNMIPrecipitationURL string
+
+	// Stations is a comma-separated list of station numbers to sync.
+	Stations string
+
 	// MigrationsDir is the path to the migrations directory.
 	MigrationsDir string
 
+	// CacheDir is the directory downloaded archives are kept in.
+	// Archives are not cached if empty.
+	CacheDir string
+
+	// APIKey authenticates requests to the KNMI Open Data API.
+	APIKey string
+
+	// OpenDataURL is the base URL of the KNMI Open Data API.
+	OpenDataURL string
+
+	// OpenDataDataset and OpenDataVersion select the Open Data API dataset
+	// the files are fetched from with --source opendata.
+	OpenDataDataset string
+	OpenDataVersion string
+
 	// Verbose enables detailed logging output.
 	Verbose bool
 }
@@ -31,13 +90,97 @@ type Config struct {
 // Load creates a Config from environment variables with sensible defaults.
 func Load() *Config {
 	return &Config{
-		DatabaseURL:   getEnv("DATABASE_URL", ""),
-		KNMIDataURL:   getEnv("KNMI_DATA_URL", DefaultKNMIDataURL),
-		MigrationsDir: getEnv("KNMI_MIGRATIONS_DIR", DefaultMigrationsDir),
-		Verbose:       true,
+		DatabaseURL:          getEnv("DATABASE_URL", ""),
+		KNMIDataURL:          getEnv("KNMI_DATA_URL", DefaultKNMIDataURL),
+		KNMIHourlyURL:        getEnv("KNMI_HOURLY_URL", DefaultKNMIHourlyURL),
+		KNMIPrecipitationURL: getEnv("KNMI_PRECIPITATION_URL", DefaultKNMIPrecipitationURL),
+		Stations:             getEnv("KNMI_STATIONS", strconv.Itoa(DefaultStation)),
+		MigrationsDir:        getEnv("KNMI_MIGRATIONS_DIR", DefaultMigrationsDir),
+		CacheDir:             getEnv("KNMI_CACHE_DIR", ""),
+		APIKey:               getEnv("KNMI_API_KEY", ""),
+		OpenDataURL:          getEnv("KNMI_OPENDATA_URL", DefaultOpenDataURL),
+		OpenDataDataset:      getEnv("KNMI_OPENDATA_DATASET", DefaultOpenDataDataset),
+		OpenDataVersion:      getEnv("KNMI_OPENDATA_VERSION", DefaultOpenDataVersion),
+		Verbose:              true,
 	}
 }
 
+// StationList returns the configured stations as station numbers.
+func (c *Config) StationList() ([]int, error) {
+	return ParseStations(c.Stations)
+}
+
+// ParseStations parses a comma-separated list of station numbers.
+// Duplicates are removed while preserving the original order.
+func ParseStations(s string) ([]int, error) {
+	var stations []int
+	seen := make(map[int]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		station, err := strconv.Atoi(part)
+		if err != nil || station <= 0 {
+			return nil, fmt.Errorf("invalid station number %q", part)
+		}
+
+		if !seen[station] {
+			seen[station] = true
+			stations = append(stations, station)
+		}
+	}
+
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("no stations configured")
+	}
+
+	return stations, nil
+}
+
+// HasStationPlaceholder reports whether a URL template contains the {station} placeholder.
+func HasStationPlaceholder(template string) bool {
+	return strings.Contains(template, StationPlaceholder)
+}
+
+// HasDecadePlaceholder reports whether a URL template contains the {decade} placeholder.
+func HasDecadePlaceholder(template string) bool {
+	return strings.Contains(template, DecadePlaceholder)
+}
+
+// StationURL returns the data URL for a station by filling in the {station} placeholder.
+func StationURL(template string, station int) string {
+	return strings.ReplaceAll(template, StationPlaceholder, strconv.Itoa(station))
+}
+
+// Decade returns the KNMI archive decade containing a year, e.g. "2021-2030"
+// for 2024. KNMI decades run from a year ending in 1 to one ending in 0.
+func Decade(year int) string {
+	start := decadeStart(year)
+	return fmt.Sprintf("%d-%d", start, start+9)
+}
+
+// Decades returns the KNMI archive decades covering the years from..to.
+func Decades(from, to int) []string {
+	var decades []string
+	for start := decadeStart(from); start <= to; start += 10 {
+		decades = append(decades, Decade(start))
+	}
+	return decades
+}
+
+// decadeStart returns the first year of the KNMI decade containing year.
+func decadeStart(year int) int {
+	return (year-1)/10*10 + 1
+}
+
+// DecadeURL returns the data URL for a decade by filling in the {decade} placeholder.
+func DecadeURL(template, decade string) string {
+	return strings.ReplaceAll(template, DecadePlaceholder, decade)
+}
+
 // getEnv returns the value of an environment variable or a default value.
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -2,6 +2,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -9,7 +10,7 @@ import (
 )
 
 // Connect establishes a connection to the PostgreSQL database.
-func Connect(databaseURL string) (*sql.DB, error) {
+func Connect(ctx context.Context, databaseURL string) (*sql.DB, error) {
 	if databaseURL == "" {
 		return nil, fmt.Errorf("database URL is required")
 	}
@@ -20,7 +21,7 @@ func Connect(databaseURL string) (*sql.DB, error) {
 	}
 
 	// Verify the connection is working
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("connecting to database: %w", err)
 	}
@@ -31,3 +32,18 @@ func Connect(databaseURL string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// TableExists checks if a table with the given name exists.
+func TableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT FROM information_schema.tables 
+			WHERE table_name = $1
+		)
+	`, table).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("checking %s table: %w", table, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// HourlyRepository manages hourly weather records in the database.
+type HourlyRepository struct {
+	db *sql.DB
+}
+
+// NewHourlyRepository creates a new hourly weather repository.
+func NewHourlyRepository(db *sql.DB) *HourlyRepository {
+	return &HourlyRepository{db: db}
+}
+
+// hourlyTable describes how hourly records are inserted.
+var hourlyTable = insertTable{
+	name: "hourly_records",
+	columns: []string{
+		"station_id", "date", "hour", "dd", "fh", "ff", "fx", "t", "t10n", "td", "sq", "q",
+		"dr", "rh", "p", "vv", "n", "u", "ww", "ix", "m", "r", "s", "o", "y", "rh_trace",
+	},
+	conflict: "station_id, date, hour",
+}
+
+// hourlyValues returns the column values of a record in hourlyTable column order.
+func hourlyValues(rec *parser.HourlyRecord) []interface{} {
+	return []interface{}{
+		rec.StationID, rec.Date, rec.Hour, rec.DD, rec.FH, rec.FF, rec.FX, rec.T, rec.T10N, rec.TD, rec.SQ, rec.Q,
+		rec.DR, rec.RH, rec.P, rec.VV, rec.N, rec.U, rec.WW, rec.IX, rec.M, rec.R, rec.S, rec.O, rec.Y, rec.RHTrace,
+	}
+}
+
+// InsertRecords inserts hourly records into the database.
+// Uses ON CONFLICT DO NOTHING to skip duplicates. Batches larger than
+// BulkInsertThreshold are loaded with COPY.
+func (r *HourlyRepository) InsertRecords(ctx context.Context, records []parser.HourlyRecord) (*InsertResult, error) {
+	rows := make([][]interface{}, len(records))
+	for i := range records {
+		rows[i] = hourlyValues(&records[i])
+	}
+	return insertRows(ctx, r.db, hourlyTable, rows)
+}
+
+// GetTotalCount returns the total number of hourly records.
+func (r *HourlyRepository) GetTotalCount(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM hourly_records").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting hourly records: %w", err)
+	}
+	return count, nil
+}
+
+// GetLatestTimes returns the end of the most recent observed hour in the
+// database for each station. Stations without any records are absent from the map.
+func (r *HourlyRepository) GetLatestTimes(ctx context.Context) (map[int]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (station_id) station_id, date, hour
+		FROM hourly_records
+		ORDER BY station_id, date DESC, hour DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("getting latest times: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make(map[int]time.Time)
+	for rows.Next() {
+		var rec parser.HourlyRecord
+		if err := rows.Scan(&rec.StationID, &rec.Date, &rec.Hour); err != nil {
+			return nil, fmt.Errorf("scanning latest time: %w", err)
+		}
+		latest[rec.StationID] = rec.Time()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating latest times: %w", err)
+	}
+
+	return latest, nil
+}
+
+// TableExists checks if the hourly_records table exists.
+func (r *HourlyRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "hourly_records")
+}
+
+// FilterHourlyAfterLatest returns only records covering an hour after the
+// latest one known for their station. Records of stations missing from
+// latest are all kept.
+func FilterHourlyAfterLatest(records []parser.HourlyRecord, latest map[int]time.Time) []parser.HourlyRecord {
+	return filterAfter(records, latest, func(r *parser.HourlyRecord) (int, time.Time) {
+		return r.StationID, r.Time()
+	})
+}
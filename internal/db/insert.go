@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// InsertResult contains the result of an insert operation.
+type InsertResult struct {
+	Inserted int
+	Updated  int
+	Skipped  int
+	Total    int
+}
+
+// Add accumulates the counts of another result, e.g. of the next batch.
+func (r *InsertResult) Add(other *InsertResult) {
+	r.Inserted += other.Inserted
+	r.Updated += other.Updated
+	r.Skipped += other.Skipped
+	r.Total += other.Total
+}
+
+// BulkInsertThreshold is the number of records above which inserts load
+// data with COPY instead of one INSERT per record.
+const BulkInsertThreshold = 500
+
+// insertTable describes a table that records are inserted into.
+// Rows always start with the station_id and date columns.
+type insertTable struct {
+	name     string
+	columns  []string
+	conflict string
+}
+
+// querier runs queries; it is implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// insertRows inserts rows into a table, skipping rows that conflict with
+// existing ones. Batches larger than BulkInsertThreshold are loaded with COPY.
+// The whole batch is applied in a single transaction, so a batch cut short,
+// e.g. by cancelling ctx, leaves the table unchanged.
+func insertRows(ctx context.Context, db *sql.DB, table insertTable, rows [][]interface{}) (*InsertResult, error) {
+	if len(rows) == 0 {
+		return &InsertResult{}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := insertRowsTx(ctx, tx, table, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// insertRowsTx inserts rows into a table within tx, as insertRows does.
+// The caller commits the transaction.
+func insertRowsTx(ctx context.Context, tx *sql.Tx, table insertTable, rows [][]interface{}) (*InsertResult, error) {
+	if len(rows) > BulkInsertThreshold {
+		return copyRows(ctx, tx, table, rows)
+	}
+
+	result := &InsertResult{
+		Total: len(rows),
+	}
+
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES (%s)
+		ON CONFLICT (%s) DO NOTHING
+	`, table.name, strings.Join(table.columns, ", "), placeholders(len(table.columns)), table.conflict)
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("preparing insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		res, err := stmt.ExecContext(ctx, row...)
+		if err != nil {
+			return nil, fmt.Errorf("inserting record %s: %w", describeRow(row), err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("getting rows affected: %w", err)
+		}
+
+		if affected > 0 {
+			result.Inserted++
+		} else {
+			result.Skipped++
+		}
+	}
+
+	return result, nil
+}
+
+// copyRows bulk loads rows with COPY into a temporary staging table, then
+// moves them into the table skipping rows that conflict with existing ones.
+// The staging table is dropped when tx commits. A staging table left by an
+// earlier call in the same transaction is replaced, so tx can load several
+// batches.
+func copyRows(ctx context.Context, tx *sql.Tx, table insertTable, rows [][]interface{}) (*InsertResult, error) {
+	result := &InsertResult{
+		Total: len(rows),
+	}
+
+	columns := strings.Join(table.columns, ", ")
+	staging := table.name + "_staging"
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", staging)); err != nil {
+		return nil, fmt.Errorf("dropping staging table: %w", err)
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TEMP TABLE %s ON COMMIT DROP AS
+		SELECT %s FROM %s WITH NO DATA
+	`, staging, columns, table.name))
+	if err != nil {
+		return nil, fmt.Errorf("creating staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("COPY %s (%s) FROM STDIN", staging, columns))
+	if err != nil {
+		return nil, fmt.Errorf("preparing copy statement: %w", err)
+	}
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return nil, fmt.Errorf("copying record %s: %w", describeRow(row), err)
+		}
+	}
+
+	// Flush the COPY stream
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return nil, fmt.Errorf("flushing copy data: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, fmt.Errorf("closing copy statement: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		SELECT %s FROM %s
+		ON CONFLICT (%s) DO NOTHING
+	`, table.name, columns, columns, staging, table.conflict))
+	if err != nil {
+		return nil, fmt.Errorf("inserting staged records: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("getting rows affected: %w", err)
+	}
+
+	result.Inserted = int(affected)
+	result.Skipped = result.Total - result.Inserted
+
+	return result, nil
+}
+
+// placeholders returns a list of n positional query parameters ($1, $2, ...).
+func placeholders(n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(params, ", ")
+}
+
+// describeRow identifies a row by its leading station_id and date values.
+func describeRow(row []interface{}) string {
+	date, _ := row[1].(time.Time)
+	return fmt.Sprintf("for station %v date %s", row[0], date.Format("2006-01-02"))
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// queryLatestDates returns the most recent date per station in a table with
+// station_id and date columns. Stations without any records are absent from the map.
+func queryLatestDates(ctx context.Context, db *sql.DB, table string) (map[int]time.Time, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT station_id, MAX(date) FROM %s GROUP BY station_id", table))
+	if err != nil {
+		return nil, fmt.Errorf("getting latest dates: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make(map[int]time.Time)
+	for rows.Next() {
+		var stationID int
+		var date time.Time
+		if err := rows.Scan(&stationID, &date); err != nil {
+			return nil, fmt.Errorf("scanning latest date: %w", err)
+		}
+		latest[stationID] = date
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating latest dates: %w", err)
+	}
+
+	return latest, nil
+}
+
+// filterAfter returns the records whose time lies after the latest time
+// known for their station, as identified by key. Records of stations
+// missing from latest are all kept.
+func filterAfter[T any](records []T, latest map[int]time.Time, key func(*T) (int, time.Time)) []T {
+	var newRecords []T
+	for i := range records {
+		stationID, at := key(&records[i])
+		cutoff, ok := latest[stationID]
+		if !ok || at.After(cutoff) {
+			newRecords = append(newRecords, records[i])
+		}
+	}
+	return newRecords
+}
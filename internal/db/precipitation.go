@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// PrecipitationRepository manages precipitation station records in the database.
+type PrecipitationRepository struct {
+	db *sql.DB
+}
+
+// NewPrecipitationRepository creates a new precipitation repository.
+func NewPrecipitationRepository(db *sql.DB) *PrecipitationRepository {
+	return &PrecipitationRepository{db: db}
+}
+
+// precipitationTable describes how precipitation records are inserted.
+var precipitationTable = insertTable{
+	name:     "precipitation_records",
+	columns:  []string{"station_id", "date", "rd", "sx"},
+	conflict: "station_id, date",
+}
+
+// InsertRecords inserts precipitation records into the database.
+// Uses ON CONFLICT DO NOTHING to skip duplicates. Batches larger than
+// BulkInsertThreshold are loaded with COPY.
+func (r *PrecipitationRepository) InsertRecords(ctx context.Context, records []parser.PrecipitationRecord) (*InsertResult, error) {
+	rows := make([][]interface{}, len(records))
+	for i, rec := range records {
+		rows[i] = []interface{}{rec.StationID, rec.Date, rec.RD, rec.SX}
+	}
+	return insertRows(ctx, r.db, precipitationTable, rows)
+}
+
+// GetTotalCount returns the total number of precipitation records.
+func (r *PrecipitationRepository) GetTotalCount(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM precipitation_records").Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting precipitation records: %w", err)
+	}
+	return count, nil
+}
+
+// GetLatestDates returns the most recent date in the database for each station.
+// Stations without any records are absent from the map.
+func (r *PrecipitationRepository) GetLatestDates(ctx context.Context) (map[int]time.Time, error) {
+	return queryLatestDates(ctx, r.db, "precipitation_records")
+}
+
+// TableExists checks if the precipitation_records table exists.
+func (r *PrecipitationRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "precipitation_records")
+}
+
+// FilterPrecipitationAfterLatest returns only records dated after the latest
+// date known for their station. Records of stations missing from latest are all kept.
+func FilterPrecipitationAfterLatest(records []parser.PrecipitationRecord, latest map[int]time.Time) []parser.PrecipitationRecord {
+	return filterAfter(records, latest, func(r *parser.PrecipitationRecord) (int, time.Time) {
+		return r.StationID, r.Date
+	})
+}
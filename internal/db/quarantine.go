@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+	"github.com/lib/pq"
+)
+
+// ErrNotQuarantined is returned when no quarantined record has the given ID.
+var ErrNotQuarantined = errors.New("quarantined record not found")
+
+// QuarantineRepository manages weather records held back by validation in
+// the weather_records_quarantine table.
+type QuarantineRepository struct {
+	db *sql.DB
+}
+
+// NewQuarantineRepository creates a new quarantine repository.
+func NewQuarantineRepository(db *sql.DB) *QuarantineRepository {
+	return &QuarantineRepository{db: db}
+}
+
+// QuarantinedRecord is a weather record together with the validation rules
+// it failed.
+type QuarantinedRecord struct {
+	ID     int
+	Record parser.WeatherRecord
+
+	// Rules are the names of the rules that fired, and Messages what each
+	// of them found.
+	Rules    []string
+	Messages []string
+
+	QuarantinedAt time.Time
+}
+
+// quarantineColumns lists the weather_records_quarantine columns, in the
+// order scanned by scanQuarantined.
+var quarantineColumns = append(append([]string{"id"}, weatherColumns...), "rules", "messages", "quarantined_at")
+
+// QuarantineTx stores records within tx. A record already in quarantine
+// for the same station and date is replaced. A record identical to the one
+// stored in weather_records, e.g. one released earlier and synced again
+// with --upsert, has already been reviewed and is not quarantined again.
+// The caller commits the transaction.
+func (r *QuarantineRepository) QuarantineTx(ctx context.Context, tx *sql.Tx, records []QuarantinedRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	candidates := make([]parser.WeatherRecord, len(records))
+	for i := range records {
+		candidates[i] = records[i].Record
+	}
+	existing, err := existingRecords(ctx, tx, candidates)
+	if err != nil {
+		return err
+	}
+
+	columns := append(append([]string(nil), weatherColumns...), "rules", "messages", "quarantined_at")
+	var updates []string
+	for _, column := range columns[2:] {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO weather_records_quarantine (%s) VALUES (%s)
+		ON CONFLICT (station_id, date) DO UPDATE SET %s
+	`, strings.Join(columns, ", "), placeholders(len(columns)), strings.Join(updates, ", "))
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("preparing quarantine insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := range records {
+		q := &records[i]
+		if stored, ok := existing[recordKey(q.Record.StationID, q.Record.Date)]; ok && len(ChangedColumns(&stored, &q.Record)) == 0 {
+			continue
+		}
+		values := append(weatherValues(&q.Record), pq.Array(q.Rules), pq.Array(q.Messages), q.QuarantinedAt)
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("quarantining record for date %s: %w", q.Record.Date.Format("2006-01-02"), err)
+		}
+	}
+
+	return nil
+}
+
+// ClearTx deletes the quarantined copies of records that have since passed
+// validation, within tx, so a corrected record synced again does not stay
+// in quarantine next to its accepted version. The caller commits the
+// transaction.
+func (r *QuarantineRepository) ClearTx(ctx context.Context, tx *sql.Tx, records []parser.WeatherRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	stationIDs := make([]int64, len(records))
+	dates := make([]string, len(records))
+	for i := range records {
+		stationIDs[i] = int64(records[i].StationID)
+		dates[i] = records[i].Date.Format("2006-01-02")
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM weather_records_quarantine q
+		USING unnest($1::integer[], $2::date[]) AS accepted (station_id, date)
+		WHERE q.station_id = accepted.station_id AND q.date = accepted.date
+	`, pq.Array(stationIDs), pq.Array(dates))
+	if err != nil {
+		return fmt.Errorf("clearing quarantined records: %w", err)
+	}
+	return nil
+}
+
+// List returns the quarantined records ordered by station and date. A
+// stationID of 0 lists the records of every station.
+func (r *QuarantineRepository) List(ctx context.Context, stationID int) ([]QuarantinedRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM weather_records_quarantine
+		WHERE $1 = 0 OR station_id = $1
+		ORDER BY station_id, date
+	`, strings.Join(quarantineColumns, ", "))
+
+	rows, err := r.db.QueryContext(ctx, query, stationID)
+	if err != nil {
+		return nil, fmt.Errorf("querying quarantined records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []QuarantinedRecord
+	for rows.Next() {
+		q, err := scanQuarantined(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating quarantined records: %w", err)
+	}
+
+	return records, nil
+}
+
+// Release moves a quarantined record into weather_records and returns it.
+// If weather_records already holds a record for the same station and date,
+// e.g. because the quarantined record is a revision synced with --upsert,
+// the stored record is updated the way UpsertRecords does and its previous
+// values are kept in weather_record_revisions. It returns ErrNotQuarantined
+// if there is no record with the ID.
+func (r *QuarantineRepository) Release(ctx context.Context, id int) (*QuarantinedRecord, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM weather_records_quarantine
+		WHERE id = $1
+		FOR UPDATE
+	`, strings.Join(quarantineColumns, ", "))
+	q, err := scanQuarantined(tx.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrNotQuarantined, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &q.Record
+	existing := make(map[string]parser.WeatherRecord)
+	query = fmt.Sprintf(`
+		SELECT %s FROM weather_records
+		WHERE station_id = $1 AND date = $2
+		FOR UPDATE
+	`, strings.Join(weatherColumns, ", "))
+	if err := scanRecords(ctx, tx, existing, query, rec.StationID, rec.Date); err != nil {
+		return nil, err
+	}
+
+	if old, exists := existing[recordKey(rec.StationID, rec.Date)]; exists {
+		if columns := ChangedColumns(&old, rec); len(columns) > 0 {
+			changes := []recordChange{{Record: *rec, Columns: columns}}
+			if err := updateRecords(ctx, tx, changes, time.Now()); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		inserted, err := insertRowsTx(ctx, tx, weatherTable, weatherRows([]parser.WeatherRecord{*rec}))
+		if err != nil {
+			return nil, err
+		}
+		if inserted.Inserted == 0 {
+			return nil, fmt.Errorf("inserting released record for station %d on %s: record was added concurrently",
+				rec.StationID, rec.Date.Format("2006-01-02"))
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM weather_records_quarantine WHERE id = $1", id); err != nil {
+		return nil, fmt.Errorf("deleting released record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return q, nil
+}
+
+// Drop deletes a quarantined record. It returns ErrNotQuarantined if there
+// is no record with the ID.
+func (r *QuarantineRepository) Drop(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM weather_records_quarantine WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("deleting quarantined record: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting quarantined record: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %d", ErrNotQuarantined, id)
+	}
+	return nil
+}
+
+// TableExists checks if the weather_records_quarantine table exists.
+func (r *QuarantineRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "weather_records_quarantine")
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanQuarantined scans a row selecting quarantineColumns.
+func scanQuarantined(row rowScanner) (*QuarantinedRecord, error) {
+	var q QuarantinedRecord
+	args := append([]interface{}{&q.ID}, weatherScanArgs(&q.Record)...)
+	args = append(args, pq.Array(&q.Rules), pq.Array(&q.Messages), &q.QuarantinedAt)
+	if err := row.Scan(args...); err != nil {
+		return nil, fmt.Errorf("scanning quarantined record: %w", err)
+	}
+	return &q, nil
+}
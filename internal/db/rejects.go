@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ParseRejectRepository keeps the data lines a sync could not parse.
+type ParseRejectRepository struct {
+	db *sql.DB
+}
+
+// NewParseRejectRepository creates a new parse reject repository.
+func NewParseRejectRepository(db *sql.DB) *ParseRejectRepository {
+	return &ParseRejectRepository{db: db}
+}
+
+// ParseReject is a malformed data line skipped in lenient parse mode.
+type ParseReject struct {
+	Dataset    string
+	SourceURL  string
+	FileName   string
+	LineNumber int
+	Column     string
+	Reason     string
+	Line       string
+	RejectedAt time.Time
+}
+
+// RecordRejects stores rejected lines in a single transaction. A line
+// already recorded for the same data file is skipped, so syncing a file
+// again keeps the time it was first rejected.
+func (r *ParseRejectRepository) RecordRejects(ctx context.Context, rejects []ParseReject) error {
+	if len(rejects) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO parse_rejects (
+			dataset, source_url, file_name, line_number, column_name, reason, line, rejected_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (dataset, source_url, (COALESCE(file_name, '')), line_number, line) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("preparing reject insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rej := range rejects {
+		_, err := stmt.ExecContext(ctx, rej.Dataset, rej.SourceURL, nullString(rej.FileName), rej.LineNumber,
+			nullString(rej.Column), rej.Reason, rej.Line, rej.RejectedAt)
+		if err != nil {
+			return fmt.Errorf("recording reject of line %d: %w", rej.LineNumber, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// TableExists checks if the parse_rejects table exists.
+func (r *ParseRejectRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "parse_rejects")
+}
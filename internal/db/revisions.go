@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Revision is a previous version of a weather record, saved when a sync
+// replaced one or more of its measurements.
+type Revision struct {
+	ID             int
+	StationID      int
+	Date           time.Time
+	Previous       map[string]*int
+	ChangedColumns []string
+	SyncedAt       time.Time
+}
+
+// insertRevisionQuery returns a query that snapshots the stored measurements
+// of the record identified by ($1 station_id, $2 date) into
+// weather_record_revisions, along with the changed columns ($3) and sync time ($4).
+// Trace flags are stored as 0 or 1 so that every previous value is an integer.
+func insertRevisionQuery() string {
+	var pairs []string
+	for _, column := range measurementColumns {
+		pairs = append(pairs, fmt.Sprintf("'%s', %s", column, column))
+	}
+	for _, column := range traceColumns {
+		pairs = append(pairs, fmt.Sprintf("'%s', %s::int", column, column))
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO weather_record_revisions (station_id, date, previous, changed_columns, synced_at)
+		SELECT station_id, date, jsonb_build_object(%s), $3, $4
+		FROM weather_records
+		WHERE station_id = $1 AND date = $2
+	`, strings.Join(pairs, ", "))
+}
+
+// GetRevisions returns the revision history of a single record, oldest first.
+func (r *WeatherRepository) GetRevisions(ctx context.Context, stationID int, date time.Time) ([]Revision, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, station_id, date, previous, changed_columns, synced_at
+		FROM weather_record_revisions
+		WHERE station_id = $1 AND date = $2
+		ORDER BY synced_at, id
+	`, stationID, date)
+	if err != nil {
+		return nil, fmt.Errorf("querying revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var rev Revision
+		var previous []byte
+		if err := rows.Scan(&rev.ID, &rev.StationID, &rev.Date, &previous, pq.Array(&rev.ChangedColumns), &rev.SyncedAt); err != nil {
+			return nil, fmt.Errorf("scanning revision: %w", err)
+		}
+		if err := json.Unmarshal(previous, &rev.Previous); err != nil {
+			return nil, fmt.Errorf("decoding previous values of revision %d: %w", rev.ID, err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating revisions: %w", err)
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SyncRunRepository records the data files processed by each sync.
+type SyncRunRepository struct {
+	db *sql.DB
+}
+
+// NewSyncRunRepository creates a new sync run repository.
+func NewSyncRunRepository(db *sql.DB) *SyncRunRepository {
+	return &SyncRunRepository{db: db}
+}
+
+// SyncRun describes one data file synced into the database.
+type SyncRun struct {
+	Dataset string
+
+	// StationID is the station the file was synced for, or 0 for a file
+	// synced for whichever stations it holds. It is stored as NULL then.
+	StationID int
+
+	SourceURL    string
+	SourceSHA256 string
+	SourceSize   int64
+	RecordsRead  int
+	Inserted     int
+	Updated      int
+	StartedAt    time.Time
+	FinishedAt   time.Time
+}
+
+// BeginTx starts a transaction for recording a sync run together with the
+// version of its source file.
+func (r *SyncRunRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// RecordRunTx stores a sync run within tx. The caller commits the
+// transaction.
+func (r *SyncRunRepository) RecordRunTx(ctx context.Context, tx *sql.Tx, run SyncRun) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO sync_runs (
+			dataset, station_id, source_url, source_sha256, source_size,
+			records_read, inserted, updated, started_at, finished_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, run.Dataset, nullInt(run.StationID), run.SourceURL, nullString(run.SourceSHA256), run.SourceSize,
+		run.RecordsRead, run.Inserted, run.Updated, run.StartedAt, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("recording sync run: %w", err)
+	}
+	return nil
+}
+
+// TableExists checks if the sync_runs table exists.
+func (r *SyncRunRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "sync_runs")
+}
+
+// nullInt converts 0 to NULL.
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SourceRepository remembers which version of each data file was synced.
+type SourceRepository struct {
+	db *sql.DB
+}
+
+// NewSourceRepository creates a new source repository.
+func NewSourceRepository(db *sql.DB) *SourceRepository {
+	return &SourceRepository{db: db}
+}
+
+// Source is the version of a data file that was last synced, identified by
+// the ETag and Last-Modified headers of its download.
+type Source struct {
+	URL          string
+	ETag         string
+	LastModified string
+	SyncedAt     time.Time
+}
+
+// GetSource returns the last synced version of the file at url, or nil if
+// it has never been synced.
+func (r *SourceRepository) GetSource(ctx context.Context, url string) (*Source, error) {
+	var src Source
+	var etag, lastModified sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT url, etag, last_modified, synced_at FROM sources WHERE url = $1",
+		url,
+	).Scan(&src.URL, &etag, &lastModified, &src.SyncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying source: %w", err)
+	}
+
+	src.ETag = etag.String
+	src.LastModified = lastModified.String
+	return &src, nil
+}
+
+// SaveSourceTx records the version of a file that was synced, within tx.
+// The caller commits the transaction.
+func (r *SourceRepository) SaveSourceTx(ctx context.Context, tx *sql.Tx, src Source) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO sources (url, etag, last_modified, synced_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (url) DO UPDATE SET
+			etag = EXCLUDED.etag,
+			last_modified = EXCLUDED.last_modified,
+			synced_at = EXCLUDED.synced_at
+	`, src.URL, nullString(src.ETag), nullString(src.LastModified), src.SyncedAt)
+	if err != nil {
+		return fmt.Errorf("saving source %s: %w", src.URL, err)
+	}
+	return nil
+}
+
+// TableExists checks if the sources table exists.
+func (r *SourceRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "sources")
+}
+
+// nullString converts an empty string to NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
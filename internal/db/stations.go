@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// StationRepository manages station metadata in the database.
+type StationRepository struct {
+	db *sql.DB
+}
+
+// NewStationRepository creates a new station repository.
+func NewStationRepository(db *sql.DB) *StationRepository {
+	return &StationRepository{db: db}
+}
+
+// StationSummary describes a station together with the range of its daily observations.
+type StationSummary struct {
+	ID        int
+	Name      *string
+	Longitude *float64
+	Latitude  *float64
+	Altitude  *float64
+	FirstDate *time.Time
+	LastDate  *time.Time
+}
+
+// UpsertStations inserts stations or updates their metadata if they exist.
+func (r *StationRepository) UpsertStations(ctx context.Context, stations []parser.Station) error {
+	if len(stations) == 0 {
+		return nil
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, `
+		INSERT INTO stations (id, name, longitude, latitude, altitude, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			longitude = EXCLUDED.longitude,
+			latitude = EXCLUDED.latitude,
+			altitude = EXCLUDED.altitude,
+			updated_at = EXCLUDED.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("preparing station upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, st := range stations {
+		if _, err := stmt.ExecContext(ctx, st.ID, st.Name, st.Longitude, st.Latitude, st.Altitude, now); err != nil {
+			return fmt.Errorf("upserting station %d: %w", st.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// EnsureStations registers station numbers that are not yet known, without
+// metadata, so records referencing them satisfy the foreign key.
+func (r *StationRepository) EnsureStations(ctx context.Context, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, "INSERT INTO stations (id) VALUES ($1) ON CONFLICT (id) DO NOTHING")
+	if err != nil {
+		return fmt.Errorf("preparing station insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.ExecContext(ctx, id); err != nil {
+			return fmt.Errorf("registering station %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// ListStations returns all stations ordered by number, with the first and
+// last date of their daily weather records.
+func (r *StationRepository) ListStations(ctx context.Context) ([]StationSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.id, s.name, s.longitude, s.latitude, s.altitude, w.first_date, w.last_date
+		FROM stations s
+		LEFT JOIN (
+			SELECT station_id, MIN(date) AS first_date, MAX(date) AS last_date
+			FROM weather_records
+			GROUP BY station_id
+		) w ON w.station_id = s.id
+		ORDER BY s.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying stations: %w", err)
+	}
+	defer rows.Close()
+
+	var stations []StationSummary
+	for rows.Next() {
+		var st StationSummary
+		if err := rows.Scan(&st.ID, &st.Name, &st.Longitude, &st.Latitude, &st.Altitude, &st.FirstDate, &st.LastDate); err != nil {
+			return nil, fmt.Errorf("scanning station: %w", err)
+		}
+		stations = append(stations, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating stations: %w", err)
+	}
+
+	return stations, nil
+}
+
+// TableExists checks if the stations table exists.
+func (r *StationRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "stations")
+}
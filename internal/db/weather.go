@@ -1,11 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/harrybawsac/knmi-go/internal/parser"
+	"github.com/lib/pq"
 )
 
 // WeatherRepository manages weather records in the database.
@@ -18,16 +21,186 @@ func NewWeatherRepository(db *sql.DB) *WeatherRepository {
 	return &WeatherRepository{db: db}
 }
 
-// InsertResult contains the result of an insert operation.
-type InsertResult struct {
-	Inserted int
-	Skipped  int
-	Total    int
+// measurementColumns lists the weather_records measurement columns,
+// in the same order as the fields returned by measurementFields.
+var measurementColumns = []string{
+	"ddvec", "fhvec", "fg", "fhx", "fhxh", "fhn", "fhnh", "fxx", "fxxh",
+	"tg", "tn", "tnh", "tx", "txh", "t10n", "t10nh", "sq", "sp", "q",
+	"dr", "rh", "rhx", "rhxh", "pg", "px", "pxh", "pn", "pnh",
+	"vvn", "vvnh", "vvx", "vvxh", "ng", "ug", "ux", "uxh", "un", "unh", "ev24",
+}
+
+// traceColumns lists the weather_records columns flagging trace
+// precipitation, in the same order as the fields returned by traceFields.
+var traceColumns = []string{"dr_trace", "rh_trace", "rhx_trace"}
+
+// weatherColumns lists the weather_records columns written on insert,
+// in the same order as the values returned by weatherValues.
+var weatherColumns = append(append([]string{"station_id", "date"}, measurementColumns...), traceColumns...)
+
+// measurementFields returns pointers to the measurement fields of a record
+// in measurementColumns order. The pointers can be used both to read values
+// and as scan destinations.
+func measurementFields(rec *parser.WeatherRecord) []**int {
+	return []**int{
+		&rec.DDVEC, &rec.FHVEC, &rec.FG, &rec.FHX, &rec.FHXH, &rec.FHN, &rec.FHNH, &rec.FXX, &rec.FXXH,
+		&rec.TG, &rec.TN, &rec.TNH, &rec.TX, &rec.TXH, &rec.T10N, &rec.T10NH, &rec.SQ, &rec.SP, &rec.Q,
+		&rec.DR, &rec.RH, &rec.RHX, &rec.RHXH, &rec.PG, &rec.PX, &rec.PXH, &rec.PN, &rec.PNH,
+		&rec.VVN, &rec.VVNH, &rec.VVX, &rec.VVXH, &rec.NG, &rec.UG, &rec.UX, &rec.UXH, &rec.UN, &rec.UNH, &rec.EV24,
+	}
+}
+
+// traceFields returns pointers to the trace flags of a record in
+// traceColumns order.
+func traceFields(rec *parser.WeatherRecord) []*bool {
+	return []*bool{&rec.DRTrace, &rec.RHTrace, &rec.RHXTrace}
+}
+
+// weatherValues returns the column values of a record in weatherColumns order.
+func weatherValues(rec *parser.WeatherRecord) []interface{} {
+	values := []interface{}{rec.StationID, rec.Date}
+	for _, field := range measurementFields(rec) {
+		values = append(values, *field)
+	}
+	for _, flag := range traceFields(rec) {
+		values = append(values, *flag)
+	}
+	return values
+}
+
+// weatherScanArgs returns scan destinations for the fields of a record in
+// weatherColumns order.
+func weatherScanArgs(rec *parser.WeatherRecord) []interface{} {
+	args := []interface{}{&rec.StationID, &rec.Date}
+	for _, field := range measurementFields(rec) {
+		args = append(args, field)
+	}
+	for _, flag := range traceFields(rec) {
+		args = append(args, flag)
+	}
+	return args
+}
+
+// ChangedColumns returns the names of the measurement and trace columns
+// whose values differ between two records of the same station and date.
+func ChangedColumns(old, updated *parser.WeatherRecord) []string {
+	oldFields := measurementFields(old)
+	newFields := measurementFields(updated)
+
+	var changed []string
+	for i, column := range measurementColumns {
+		a, b := *oldFields[i], *newFields[i]
+		if (a == nil) != (b == nil) || (a != nil && *a != *b) {
+			changed = append(changed, column)
+		}
+	}
+
+	oldFlags := traceFields(old)
+	newFlags := traceFields(updated)
+	for i, column := range traceColumns {
+		if *oldFlags[i] != *newFlags[i] {
+			changed = append(changed, column)
+		}
+	}
+	return changed
+}
+
+// MeasurementValues returns the measurement values of a record keyed by
+// column name. Trace flags are included as 0 or 1, matching how they are
+// stored in revisions.
+func MeasurementValues(rec *parser.WeatherRecord) map[string]*int {
+	values := make(map[string]*int, len(measurementColumns)+len(traceColumns))
+	for i, field := range measurementFields(rec) {
+		values[measurementColumns[i]] = *field
+	}
+	for i, flag := range traceFields(rec) {
+		v := 0
+		if *flag {
+			v = 1
+		}
+		values[traceColumns[i]] = &v
+	}
+	return values
+}
+
+// recordKey returns the (station_id, date) key identifying a record.
+func recordKey(stationID int, date time.Time) string {
+	return fmt.Sprintf("%d:%s", stationID, date.Format("2006-01-02"))
+}
+
+// weatherTable describes how weather records are inserted.
+var weatherTable = insertTable{
+	name:     "weather_records",
+	columns:  weatherColumns,
+	conflict: "station_id, date",
 }
 
 // InsertRecords inserts weather records into the database.
-// Uses ON CONFLICT DO NOTHING to skip duplicates.
-func (r *WeatherRepository) InsertRecords(records []parser.WeatherRecord) (*InsertResult, error) {
+// Uses ON CONFLICT DO NOTHING to skip duplicates. Batches larger than
+// BulkInsertThreshold are loaded with COPY.
+func (r *WeatherRepository) InsertRecords(ctx context.Context, records []parser.WeatherRecord) (*InsertResult, error) {
+	return insertRows(ctx, r.db, weatherTable, weatherRows(records))
+}
+
+// InsertRecordsTx inserts weather records within tx, as InsertRecords
+// does. The caller commits the transaction.
+func (r *WeatherRepository) InsertRecordsTx(ctx context.Context, tx *sql.Tx, records []parser.WeatherRecord) (*InsertResult, error) {
+	return insertRowsTx(ctx, tx, weatherTable, weatherRows(records))
+}
+
+// BeginTx starts a transaction for writing a batch of records together
+// with related rows, such as the quarantined records of the same batch.
+func (r *WeatherRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// weatherRows returns the weatherValues of each record.
+func weatherRows(records []parser.WeatherRecord) [][]interface{} {
+	rows := make([][]interface{}, len(records))
+	for i := range records {
+		rows[i] = weatherValues(&records[i])
+	}
+	return rows
+}
+
+// UpsertRecords inserts new records and updates existing records whose
+// measurements differ from the stored values. Unchanged records are skipped.
+// This lets corrections published by KNMI after quality control replace
+// previously synced values.
+//
+// The existence check, inserts, updates and revision rows of the batch are
+// applied in a single transaction, so a batch cut short, e.g. by cancelling
+// ctx, leaves both weather_records and weather_record_revisions unchanged.
+func (r *WeatherRepository) UpsertRecords(ctx context.Context, records []parser.WeatherRecord) (*InsertResult, error) {
+	if len(records) == 0 {
+		return &InsertResult{}, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := r.UpsertRecordsTx(ctx, tx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpsertRecordsTx upserts weather records within tx, as UpsertRecords
+// does. The caller commits the transaction.
+func (r *WeatherRepository) UpsertRecordsTx(ctx context.Context, tx *sql.Tx, records []parser.WeatherRecord) (*InsertResult, error) {
 	result := &InsertResult{
 		Total: len(records),
 	}
@@ -36,129 +209,254 @@ func (r *WeatherRepository) InsertRecords(records []parser.WeatherRecord) (*Inse
 		return result, nil
 	}
 
-	query := `
-		INSERT INTO weather_records (
-			station_id, date, ddvec, fhvec, fg, fhx, fhxh, fhn, fhnh, fxx, fxxh,
-			tg, tn, tnh, tx, txh, t10n, t10nh, sq, sp, q,
-			dr, rh, rhx, rhxh, pg, px, pxh, pn, pnh,
-			vvn, vvnh, vvx, vvxh, ng, ug, ux, uxh, un, unh, ev24
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
-			$12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
-			$22, $23, $24, $25, $26, $27, $28, $29, $30,
-			$31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41
-		)
-		ON CONFLICT (station_id, date) DO NOTHING
-	`
-
-	stmt, err := r.db.Prepare(query)
+	existing, err := existingRecords(ctx, tx, records)
 	if err != nil {
-		return nil, fmt.Errorf("preparing insert statement: %w", err)
+		return nil, err
 	}
-	defer stmt.Close()
 
+	// Split into records to insert and records whose values were revised
+	var newRecords []parser.WeatherRecord
+	var changes []recordChange
 	for _, rec := range records {
-		res, err := stmt.Exec(
-			rec.StationID, rec.Date,
-			rec.DDVEC, rec.FHVEC, rec.FG, rec.FHX, rec.FHXH, rec.FHN, rec.FHNH, rec.FXX, rec.FXXH,
-			rec.TG, rec.TN, rec.TNH, rec.TX, rec.TXH, rec.T10N, rec.T10NH, rec.SQ, rec.SP, rec.Q,
-			rec.DR, rec.RH, rec.RHX, rec.RHXH, rec.PG, rec.PX, rec.PXH, rec.PN, rec.PNH,
-			rec.VVN, rec.VVNH, rec.VVX, rec.VVXH, rec.NG, rec.UG, rec.UX, rec.UXH, rec.UN, rec.UNH, rec.EV24,
-		)
-		if err != nil {
-			return result, fmt.Errorf("inserting record for date %s: %w", rec.Date.Format("2006-01-02"), err)
-		}
-
-		affected, err := res.RowsAffected()
-		if err != nil {
-			return result, fmt.Errorf("getting rows affected: %w", err)
+		old, exists := existing[recordKey(rec.StationID, rec.Date)]
+		if !exists {
+			newRecords = append(newRecords, rec)
+			continue
 		}
-
-		if affected > 0 {
-			result.Inserted++
+		if columns := ChangedColumns(&old, &rec); len(columns) > 0 {
+			changes = append(changes, recordChange{Record: rec, Columns: columns})
 		} else {
 			result.Skipped++
 		}
 	}
 
+	inserted, err := r.InsertRecordsTx(ctx, tx, newRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateRecords(ctx, tx, changes, time.Now()); err != nil {
+		return nil, err
+	}
+
+	result.Inserted = inserted.Inserted
+	result.Skipped += inserted.Skipped
+	result.Updated = len(changes)
 	return result, nil
 }
 
+// recordChange is a parsed record together with the columns that differ
+// from the stored version.
+type recordChange struct {
+	Record  parser.WeatherRecord
+	Columns []string
+}
+
+// existingRecords loads the stored records covering the stations and date
+// ranges of the given records, keyed by recordKey.
+func existingRecords(ctx context.Context, q querier, records []parser.WeatherRecord) (map[string]parser.WeatherRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM weather_records
+		WHERE station_id = $1 AND date BETWEEN $2 AND $3
+	`, strings.Join(weatherColumns, ", "))
+
+	existing := make(map[string]parser.WeatherRecord)
+	for stationID, rng := range stationDateRanges(records) {
+		if err := scanRecords(ctx, q, existing, query, stationID, rng.from, rng.to); err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
+
+// dateRange is the first and last date of a station's records.
+type dateRange struct{ from, to time.Time }
+
+// stationDateRanges returns the date range of the records of each station.
+func stationDateRanges(records []parser.WeatherRecord) map[int]*dateRange {
+	ranges := make(map[int]*dateRange)
+	for _, rec := range records {
+		rng, ok := ranges[rec.StationID]
+		if !ok {
+			ranges[rec.StationID] = &dateRange{from: rec.Date, to: rec.Date}
+			continue
+		}
+		if rec.Date.Before(rng.from) {
+			rng.from = rec.Date
+		}
+		if rec.Date.After(rng.to) {
+			rng.to = rec.Date
+		}
+	}
+	return ranges
+}
+
+// scanRecords runs a query selecting weatherColumns and adds the resulting
+// records to dest, keyed by recordKey.
+func scanRecords(ctx context.Context, q querier, dest map[string]parser.WeatherRecord, query string, args ...interface{}) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("querying existing records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rec parser.WeatherRecord
+		if err := rows.Scan(weatherScanArgs(&rec)...); err != nil {
+			return fmt.Errorf("scanning existing record: %w", err)
+		}
+		dest[recordKey(rec.StationID, rec.Date)] = rec
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating existing records: %w", err)
+	}
+
+	return nil
+}
+
+// updateRecords overwrites the measurements and trace flags of existing
+// records within tx. The previous values of every record are kept in
+// weather_record_revisions, stamped with syncedAt.
+func updateRecords(ctx context.Context, tx *sql.Tx, changes []recordChange, syncedAt time.Time) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Every column after station_id and date, in weatherValues order
+	updated := weatherColumns[2:]
+	assignments := make([]string, len(updated))
+	for i, column := range updated {
+		assignments[i] = fmt.Sprintf("%s = $%d", column, i+3)
+	}
+	query := fmt.Sprintf(`
+		UPDATE weather_records SET %s
+		WHERE station_id = $1 AND date = $2
+	`, strings.Join(assignments, ", "))
+
+	revisionStmt, err := tx.PrepareContext(ctx, insertRevisionQuery())
+	if err != nil {
+		return fmt.Errorf("preparing revision statement: %w", err)
+	}
+	defer revisionStmt.Close()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("preparing update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := range changes {
+		rec := &changes[i].Record
+		date := rec.Date.Format("2006-01-02")
+
+		if _, err := revisionStmt.ExecContext(ctx, rec.StationID, rec.Date, pq.Array(changes[i].Columns), syncedAt); err != nil {
+			return fmt.Errorf("recording revision for date %s: %w", date, err)
+		}
+		if _, err := stmt.ExecContext(ctx, weatherValues(rec)...); err != nil {
+			return fmt.Errorf("updating record for date %s: %w", date, err)
+		}
+	}
+
+	return nil
+}
+
 // GetTotalCount returns the total number of weather records.
-func (r *WeatherRepository) GetTotalCount() (int, error) {
+func (r *WeatherRepository) GetTotalCount(ctx context.Context) (int, error) {
 	var count int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count)
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM weather_records").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("counting weather records: %w", err)
 	}
 	return count, nil
 }
 
-// GetLatestDate returns the most recent date in the database.
-func (r *WeatherRepository) GetLatestDate() (*time.Time, error) {
-	var date sql.NullTime
-	err := r.db.QueryRow("SELECT MAX(date) FROM weather_records").Scan(&date)
-	if err != nil {
-		return nil, fmt.Errorf("getting latest date: %w", err)
+// GetRecord returns the stored record for a station and date, or nil if none exists.
+func (r *WeatherRepository) GetRecord(ctx context.Context, stationID int, date time.Time) (*parser.WeatherRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM weather_records
+		WHERE station_id = $1 AND date = $2
+	`, strings.Join(weatherColumns, ", "))
+
+	found := make(map[string]parser.WeatherRecord)
+	if err := scanRecords(ctx, r.db, found, query, stationID, date); err != nil {
+		return nil, err
 	}
-	if !date.Valid {
+
+	rec, ok := found[recordKey(stationID, date)]
+	if !ok {
 		return nil, nil
 	}
-	return &date.Time, nil
+	return &rec, nil
+}
+
+// GetLatestDates returns the most recent date in the database for each station.
+// Stations without any records are absent from the map.
+func (r *WeatherRepository) GetLatestDates(ctx context.Context) (map[int]time.Time, error) {
+	return queryLatestDates(ctx, r.db, "weather_records")
+}
+
+// FilterAfterLatest returns only records dated after the latest date known
+// for their station. Records of stations missing from latest are all kept.
+func FilterAfterLatest(records []parser.WeatherRecord, latest map[int]time.Time) []parser.WeatherRecord {
+	return filterAfter(records, latest, func(r *parser.WeatherRecord) (int, time.Time) {
+		return r.StationID, r.Date
+	})
 }
 
 // TableExists checks if the weather_records table exists.
-func (r *WeatherRepository) TableExists() (bool, error) {
-	var exists bool
-	err := r.db.QueryRow(`
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_name = 'weather_records'
-		)
-	`).Scan(&exists)
-	if err != nil {
-		return false, fmt.Errorf("checking weather_records table: %w", err)
-	}
-	return exists, nil
+func (r *WeatherRepository) TableExists(ctx context.Context) (bool, error) {
+	return TableExists(ctx, r.db, "weather_records")
 }
 
 // FilterNewRecords returns only records that don't already exist in the database.
-// Uses the (station_id, date) composite key to check for existing records.
-func (r *WeatherRepository) FilterNewRecords(records []parser.WeatherRecord) ([]parser.WeatherRecord, error) {
+// Uses the (station_id, date) composite key to check for existing records,
+// looking up only the keys within the date range of each station's records.
+func (r *WeatherRepository) FilterNewRecords(ctx context.Context, records []parser.WeatherRecord) ([]parser.WeatherRecord, error) {
 	if len(records) == 0 {
 		return records, nil
 	}
 
-	// Query all existing (station_id, date) pairs
-	rows, err := r.db.Query("SELECT station_id, date FROM weather_records")
-	if err != nil {
-		return nil, fmt.Errorf("querying existing records: %w", err)
-	}
-	defer rows.Close()
-
-	// Build a set of existing keys
+	// Build a set of the existing keys in range
 	existing := make(map[string]struct{})
-	for rows.Next() {
-		var stationID int
-		var date time.Time
-		if err := rows.Scan(&stationID, &date); err != nil {
-			return nil, fmt.Errorf("scanning existing record: %w", err)
+	for stationID, rng := range stationDateRanges(records) {
+		if err := r.existingKeys(ctx, existing, stationID, rng); err != nil {
+			return nil, err
 		}
-		key := fmt.Sprintf("%d:%s", stationID, date.Format("2006-01-02"))
-		existing[key] = struct{}{}
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating existing records: %w", err)
 	}
 
 	// Filter to only new records
 	var newRecords []parser.WeatherRecord
 	for _, rec := range records {
-		key := fmt.Sprintf("%d:%s", rec.StationID, rec.Date.Format("2006-01-02"))
-		if _, exists := existing[key]; !exists {
+		if _, exists := existing[recordKey(rec.StationID, rec.Date)]; !exists {
 			newRecords = append(newRecords, rec)
 		}
 	}
 
 	return newRecords, nil
 }
+
+// existingKeys adds the keys of the stored records of a station within a
+// date range to dest.
+func (r *WeatherRepository) existingKeys(ctx context.Context, dest map[string]struct{}, stationID int, rng *dateRange) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT date FROM weather_records
+		WHERE station_id = $1 AND date BETWEEN $2 AND $3
+	`, stationID, rng.from, rng.to)
+	if err != nil {
+		return fmt.Errorf("querying existing records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return fmt.Errorf("scanning existing record: %w", err)
+		}
+		dest[recordKey(stationID, date)] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating existing records: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotCached is returned by Cache.Latest when no copy of a URL is cached.
+var ErrNotCached = errors.New("no cached copy")
+
+// cacheTimeFormat is the timestamp format of cached file names. It sorts
+// chronologically.
+const cacheTimeFormat = "20060102T150405Z"
+
+// unsafeNameChars matches characters replaced in cache directory names.
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Cache keeps downloaded archives in a local directory so they can be
+// processed again without network access.
+//
+// Each URL has its own subdirectory, named after the file in the URL and a
+// hash of the full URL. Archives are stored as "<timestamp>-<sha256>.zip",
+// where the hash is that of the archive content.
+type Cache struct {
+	dir string
+}
+
+// NewCache creates a cache storing archives in dir.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Store copies an archive downloaded from url at the given time into the
+// cache and returns its path. An archive whose content is already cached
+// for the URL is not stored again; the existing copy is renamed to the new
+// time instead, so it is the newest copy again.
+func (c *Cache) Store(rawURL string, r io.Reader, at time.Time) (string, error) {
+	dir := c.urlDir(rawURL)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("creating cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), r); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("writing cache file: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	name := filepath.Join(dir, at.UTC().Format(cacheTimeFormat)+"-"+sum+".zip")
+
+	// Move the existing copy of identical content to the new time
+	existing, err := filepath.Glob(filepath.Join(dir, "*-"+sum+".zip"))
+	if err != nil {
+		return "", fmt.Errorf("reading cache directory: %w", err)
+	}
+	if len(existing) > 0 {
+		if err := os.Rename(existing[0], name); err != nil {
+			return "", fmt.Errorf("storing cache file: %w", err)
+		}
+		return name, nil
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", fmt.Errorf("storing cache file: %w", err)
+	}
+
+	return name, nil
+}
+
+// Latest returns the path of the newest cached archive of url. It returns
+// an error wrapping ErrNotCached if there is none.
+func (c *Cache) Latest(rawURL string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(c.urlDir(rawURL), "*.zip"))
+	if err != nil {
+		return "", fmt.Errorf("reading cache directory: %w", err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("%w of %s in %s", ErrNotCached, rawURL, c.dir)
+	}
+
+	sort.Strings(files)
+	return files[len(files)-1], nil
+}
+
+// urlDir returns the cache directory of a URL.
+func (c *Cache) urlDir(rawURL string) string {
+	name := "archive"
+	if u, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	name = strings.Trim(unsafeNameChars.ReplaceAllString(name, "_"), "_")
+
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, name+"-"+hex.EncodeToString(sum[:4]))
+}
@@ -0,0 +1,489 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default retry settings used by NewClient.
+const (
+	DefaultTimeout    = 60 * time.Second
+	DefaultRetries    = 3
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Client downloads KNMI archives over HTTP. Failed requests are retried
+// with jittered exponential backoff, and downloads interrupted part way
+// are resumed with an HTTP Range request rather than started over.
+type Client struct {
+	// HTTPClient performs the requests. Its Timeout bounds each attempt.
+	HTTPClient *http.Client
+
+	// Retries is the number of times a failed request is retried.
+	// Network errors and 5xx or 429 responses are retried; other
+	// responses fail immediately.
+	Retries int
+
+	// MinBackoff is the delay before the first retry. It doubles with
+	// every further retry, up to MaxBackoff.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+
+	// Cache, if set, keeps a copy of the archives downloaded by the
+	// client that are saved with Archive.SaveToCache.
+	Cache *Cache
+
+	// MaxExtractedSize limits the size of a data file extracted from a zip
+	// archive, guarding against zip bombs. Zero uses
+	// DefaultMaxExtractedSize; a negative size disables the limit.
+	MaxExtractedSize int64
+
+	// DataFilePattern is the glob pattern, as used by path.Match, that
+	// selects the data files of a zip archive. A pattern without a "/" is
+	// matched against the base name of each file. Empty uses
+	// DefaultDataFilePattern.
+	DataFilePattern string
+
+	// MaxStreamSize limits the size of a zip archive read from a stream
+	// such as standard input, which is spooled to a temporary file, and of
+	// a data file streamed from one that is spooled to be verified. Zero
+	// uses DefaultMaxStreamSize; a negative size disables the limit.
+	MaxStreamSize int64
+
+	// OnRetry, if set, is called before each retry with the attempt about
+	// to be made (starting at 2), the delay before it and the error that
+	// caused it.
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// ErrNotModified is returned by OpenIfModified when the file has not
+// changed since the validators of the previous download.
+var ErrNotModified = errors.New("source not modified")
+
+// HTTPError is returned for a response with an unexpected status code.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error: %d %s", e.StatusCode, e.Status)
+}
+
+// Validators identify a version of a remote file, as returned in the ETag
+// and Last-Modified response headers.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// IsZero reports whether no validators are known.
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// setConditional makes req conditional on the file having changed.
+func (v Validators) setConditional(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// ifRange returns the value for an If-Range header, preferring the ETag.
+func (v Validators) ifRange() string {
+	if v.ETag != "" {
+		return v.ETag
+	}
+	return v.LastModified
+}
+
+// validatorsOf returns the validators of a response.
+func validatorsOf(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// DefaultClient is used by the package-level Download and Open functions.
+// It makes a single attempt without a timeout.
+var DefaultClient = &Client{}
+
+// NewClient creates a client with the given per-request timeout and number
+// of retries. A zero timeout means no timeout.
+func NewClient(timeout time.Duration, retries int) *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: timeout},
+		Retries:    retries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// Download fetches data from the given URL. File URLs are read from disk.
+func (c *Client) Download(ctx context.Context, url string) ([]byte, error) {
+	if IsFileURL(url) {
+		path, err := FilePath(url)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading data file: %w", err)
+		}
+		return data, nil
+	}
+
+	buf := &bufferSink{}
+	if _, err := c.fetch(ctx, url, buf, Validators{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open downloads the zip archive at url and opens its first data file for
+// reading. The archive is spooled to a temporary file rather than held in
+// memory; Close removes it.
+func (c *Client) Open(ctx context.Context, url string) (*Archive, error) {
+	return c.OpenIfModified(ctx, url, Validators{})
+}
+
+// OpenIfModified is like Open, but makes a conditional request with the
+// validators of a previous download. Once the archive has been verified,
+// Archive.SaveToCache stores it in the client's cache, if any.
+//
+// If the archive has not changed since, it returns ErrNotModified without
+// downloading it. File URLs are opened with OpenFile, so they may also point
+// at an already extracted data file; they are neither cached nor checked for
+// changes.
+//
+// Cancelling ctx aborts the download, including any wait before a retry.
+func (c *Client) OpenIfModified(ctx context.Context, url string, since Validators) (*Archive, error) {
+	if IsFileURL(url) {
+		path, err := FilePath(url)
+		if err != nil {
+			return nil, err
+		}
+		return c.OpenFile(path)
+	}
+
+	return c.openDownload(ctx, url, url, since)
+}
+
+// openDownload downloads the archive at url into a temporary file and opens
+// it. Archive.SaveToCache stores a copy in the cache, if any, under cacheURL.
+func (c *Client) openDownload(ctx context.Context, url, cacheURL string, since Validators) (*Archive, error) {
+	file, err := os.CreateTemp("", "knmi-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	archive := &Archive{file: file, temporary: true, limit: c.maxExtractedSize()}
+	d, err := c.fetch(ctx, url, &fileSink{file}, since)
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+	archive.Size = d.written
+	archive.Validators = d.validators
+
+	archive.sha256, err = hashReader(file, archive.Size)
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	if err := archive.openZip(file, archive.Size, c.dataFilePattern()); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	archive.cache = c.Cache
+	archive.cacheURL = cacheURL
+	return archive, nil
+}
+
+// sink receives a download and can discard it to start over.
+type sink interface {
+	io.Writer
+	reset() error
+}
+
+// bufferSink collects a download in memory.
+type bufferSink struct {
+	bytes.Buffer
+}
+
+func (b *bufferSink) reset() error {
+	b.Reset()
+	return nil
+}
+
+// fileSink writes a download to a file.
+type fileSink struct {
+	*os.File
+}
+
+func (f *fileSink) reset() error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	return err
+}
+
+// errRetryable marks an error that a new attempt may resolve.
+type errRetryable struct {
+	err error
+}
+
+func (e *errRetryable) Error() string { return e.err.Error() }
+func (e *errRetryable) Unwrap() error { return e.err }
+
+// download tracks the progress of fetching a URL across attempts.
+type download struct {
+	url string
+	dst sink
+
+	// since holds the validators sent with a conditional request.
+	since Validators
+
+	// validators identify the version of the file being downloaded.
+	validators Validators
+
+	// written is the number of bytes dst holds.
+	written int64
+
+	// header holds additional request headers, such as an API key.
+	header http.Header
+}
+
+// fetch downloads url into dst, retrying and resuming as configured.
+func (c *Client) fetch(ctx context.Context, url string, dst sink, since Validators) (*download, error) {
+	d := &download{url: url, dst: dst, since: since}
+	if err := c.run(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// run performs a download, retrying and resuming as configured. A
+// cancelled context is never retried and cuts short the wait between
+// attempts.
+func (c *Client) run(ctx context.Context, d *download) error {
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, d)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("download of %s interrupted: %w", d.url, context.Cause(ctx))
+		}
+
+		var retryable *errRetryable
+		if !errors.As(err, &retryable) {
+			return err
+		}
+		if attempt > c.Retries {
+			if c.Retries > 0 {
+				return fmt.Errorf("giving up after %d attempts: %w", attempt, retryable.err)
+			}
+			return retryable.err
+		}
+
+		delay := c.backoff(attempt)
+		if c.OnRetry != nil {
+			c.OnRetry(attempt+1, delay, retryable.err)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return fmt.Errorf("download of %s interrupted: %w", d.url, err)
+		}
+	}
+}
+
+// sleep waits for the given delay, returning early with the cause of the
+// cancellation if ctx is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// attempt makes a single request for the download, resuming after the bytes
+// already written. Resumed requests carry the validators of the first
+// response in If-Range, so a changed archive is downloaded again in full.
+// Without validators a change cannot be detected, so the download restarts
+// from the first byte instead of resuming.
+func (c *Client) attempt(ctx context.Context, d *download) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if d.written > 0 && d.validators.IsZero() {
+		if err := d.restart(); err != nil {
+			return err
+		}
+	}
+	for key, values := range d.header {
+		req.Header[key] = values
+	}
+	if d.written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.written))
+		if v := d.validators.ifRange(); v != "" {
+			req.Header.Set("If-Range", v)
+		}
+	} else {
+		d.since.setConditional(req)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return &errRetryable{fmt.Errorf("HTTP request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	// A partial response continues the download; a full response replaces
+	// it. A range that does not start where we stopped, or that was not
+	// asked for, restarts it
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && d.written > 0 && rangeStart(resp) == d.written:
+	case resp.StatusCode == http.StatusOK:
+		if d.written > 0 {
+			if err := d.restart(); err != nil {
+				return err
+			}
+		}
+		d.validators = validatorsOf(resp)
+	case resp.StatusCode == http.StatusNotModified && d.written == 0:
+		return ErrNotModified
+	case resp.StatusCode == http.StatusPartialContent:
+		expected := d.written
+		if err := d.restart(); err != nil {
+			return err
+		}
+		return &errRetryable{fmt.Errorf("server sent a partial response starting at byte %d, expected %d", rangeStart(resp), expected)}
+	default:
+		err := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return &errRetryable{err}
+		}
+		return err
+	}
+
+	n, err := io.Copy(d.dst, resp.Body)
+	d.written += n
+	if err != nil {
+		return &errRetryable{fmt.Errorf("reading response body: %w", err)}
+	}
+
+	return nil
+}
+
+// restart discards the bytes downloaded so far.
+func (d *download) restart() error {
+	if err := d.dst.reset(); err != nil {
+		return fmt.Errorf("restarting download: %w", err)
+	}
+	d.written = 0
+	return nil
+}
+
+// rangeStart returns the first byte position of a partial response's
+// Content-Range header ("bytes 100-199/200"), or -1 if it is missing.
+func rangeStart(resp *http.Response) int64 {
+	spec, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// backoff returns the delay before retrying after the given attempt: the
+// minimum backoff doubled per attempt, capped at the maximum, with jitter
+// so concurrent clients do not retry in lockstep.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.MinBackoff
+	for i := 1; i < attempt && delay < c.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if c.MaxBackoff > 0 && delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Full delay halved, plus up to half at random
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// maxExtractedSize returns the limit on the size of an extracted data
+// file, or 0 if there is none.
+func (c *Client) maxExtractedSize() int64 {
+	switch {
+	case c.MaxExtractedSize < 0:
+		return 0
+	case c.MaxExtractedSize == 0:
+		return DefaultMaxExtractedSize
+	}
+	return c.MaxExtractedSize
+}
+
+// maxStreamSize returns the limit on the size of a zip archive read from a
+// stream, or 0 if there is none.
+func (c *Client) maxStreamSize() int64 {
+	switch {
+	case c.MaxStreamSize < 0:
+		return 0
+	case c.MaxStreamSize == 0:
+		return DefaultMaxStreamSize
+	}
+	return c.MaxStreamSize
+}
+
+// dataFilePattern returns the pattern selecting the data files of a zip
+// archive.
+func (c *Client) dataFilePattern() string {
+	if c.DataFilePattern != "" {
+		return c.DataFilePattern
+	}
+	return DefaultDataFilePattern
+}
+
+// httpClient returns the HTTP client to use for requests.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -1,58 +1,463 @@
-// Package fetch provides HTTP download and zip extraction functionality.
+// Package fetch provides HTTP download, KNMI Open Data API, local file and
+// zip extraction functionality.
 package fetch
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
-// Download fetches data from the given URL.
-func Download(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+// Download fetches data from the given URL using DefaultClient.
+func Download(ctx context.Context, url string) ([]byte, error) {
+	return DefaultClient.Download(ctx, url)
+}
+
+// DefaultDataFilePattern matches the data files of a zip archive.
+const DefaultDataFilePattern = "*.txt"
+
+// ExtractedFile is a data file extracted from a zip archive.
+type ExtractedFile struct {
+	Name string
+	Data []byte
+}
+
+// ExtractZip extracts the first data file from a zip archive.
+func ExtractZip(data []byte) ([]byte, error) {
+	rc, err := OpenZip(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	defer rc.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	return content, nil
+}
+
+// ExtractZipFiles extracts every file matching pattern from a zip archive,
+// in the order they are stored. An empty pattern uses DefaultDataFilePattern.
+// Each file may extract to at most DefaultMaxExtractedSize bytes.
+func ExtractZipFiles(data []byte, pattern string) ([]ExtractedFile, error) {
+	if pattern == "" {
+		pattern = DefaultDataFilePattern
+	}
+
+	files, err := zipDataFiles(bytes.NewReader(data), int64(len(data)), pattern)
 	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
+		return nil, err
+	}
+
+	extracted := make([]ExtractedFile, 0, len(files))
+	for _, f := range files {
+		rc, err := openZipFile(f, DefaultMaxExtractedSize)
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		extracted = append(extracted, ExtractedFile{Name: f.Name, Data: content})
 	}
 
-	return data, nil
+	return extracted, nil
 }
 
-// ExtractZip extracts the first .txt file from a zip archive.
-func ExtractZip(data []byte) ([]byte, error) {
-	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+// OpenZip opens the first file matching DefaultDataFilePattern in a zip
+// archive of the given size for reading. The file is decompressed as it is
+// read, and reading fails once it exceeds DefaultMaxExtractedSize or if its
+// data is corrupt.
+func OpenZip(r io.ReaderAt, size int64) (io.ReadCloser, error) {
+	files, err := zipDataFiles(r, size, DefaultDataFilePattern)
+	if err != nil {
+		return nil, err
+	}
+	return openZipFile(files[0], DefaultMaxExtractedSize)
+}
+
+// zipDataFiles returns the files of a zip archive that match pattern, a
+// glob pattern as used by path.Match. A pattern without a "/" is matched
+// against the base name of each file.
+func zipDataFiles(r io.ReaderAt, size int64, pattern string) ([]*zip.File, error) {
+	reader, err := zip.NewReader(r, size)
+	if errors.Is(err, zip.ErrFormat) {
+		return nil, fmt.Errorf("opening zip: archive is corrupt or truncated: %w", err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("opening zip: %w", err)
 	}
 
+	var files []*zip.File
 	for _, f := range reader.File {
-		if strings.HasSuffix(f.Name, ".txt") {
-			rc, err := f.Open()
-			if err != nil {
-				return nil, fmt.Errorf("opening file %s in zip: %w", f.Name, err)
-			}
-			defer rc.Close()
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		ok, err := matchDataFile(pattern, f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = append(files, f)
+		}
+	}
+
+	if len(files) == 0 {
+		if pattern == DefaultDataFilePattern {
+			return nil, fmt.Errorf("no .txt file found in zip archive")
+		}
+		return nil, fmt.Errorf("no file matching %q found in zip archive", pattern)
+	}
+	return files, nil
+}
+
+// matchDataFile reports whether the zip file name matches pattern.
+func matchDataFile(pattern, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	ok, err := path.Match(pattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid data file pattern %q: %w", pattern, err)
+	}
+	return ok, nil
+}
 
-			content, err := io.ReadAll(rc)
-			if err != nil {
-				return nil, fmt.Errorf("reading file %s from zip: %w", f.Name, err)
-			}
+// openZipFile opens a file of a zip archive, guarding against files that
+// extract to more than limit bytes. A limit of 0 disables the check.
+func openZipFile(f *zip.File, limit int64) (io.ReadCloser, error) {
+	if limit > 0 && f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%w: %s extracts to %d bytes, more than %d", ErrTooLarge, f.Name, f.UncompressedSize64, limit)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening file %s in zip: %w", f.Name, err)
+	}
+	return &zipEntryReader{rc: rc, name: f.Name, limit: limit}, nil
+}
+
+// Archive streams the data files of a zip archive one after another, or a
+// plain text data file. Reading starts with the first data file; NextFile
+// moves on to the next.
+type Archive struct {
+	io.ReadCloser
+
+	// Size is the size of the downloaded archive in bytes. For a data file
+	// streamed from a reader it is the number of bytes read so far.
+	Size int64
+
+	// Validators identify the downloaded version of the archive for
+	// conditional requests by a later sync.
+	Validators Validators
+
+	file *os.File
+
+	// temporary reports whether file is removed on Close.
+	temporary bool
+
+	// sha256 is the hash of the archive, if it was known when opened.
+	sha256 string
+
+	// hashing hashes a data file streamed from a reader as it is read.
+	hashing *hashingReader
+
+	// name is the name of the data file being read.
+	name string
+
+	// limit is the size a data file may extract to, or 0 for no limit.
+	limit int64
+
+	// streamLimit is the size a data file streamed from a reader may be
+	// spooled to for verification, or 0 or less for no limit.
+	streamLimit int64
+
+	// cache, if set, stores a downloaded archive under cacheURL.
+	cache    *Cache
+	cacheURL string
+
+	// pending are the data files of a zip archive that have not been read yet.
+	pending []*zip.File
+}
+
+// Open downloads the zip archive at url using DefaultClient and opens its
+// first data file for reading. Close removes the downloaded archive.
+func Open(ctx context.Context, url string) (*Archive, error) {
+	return DefaultClient.Open(ctx, url)
+}
+
+// OpenFile opens a local data file for reading using DefaultClient.
+func OpenFile(path string) (*Archive, error) {
+	return DefaultClient.OpenFile(path)
+}
 
-			return content, nil
+// OpenFile opens a local data file for reading. Zip archives are detected
+// by their content and their first data file is opened; any other file is
+// read as an already extracted data file.
+func (c *Client) OpenFile(path string) (*Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening data file: %w", err)
+	}
+
+	archive := &Archive{file: file, limit: c.maxExtractedSize()}
+	info, err := file.Stat()
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("opening data file: %w", err)
+	}
+	archive.Size = info.Size()
+
+	archive.sha256, err = hashReader(file, archive.Size)
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	isZip, err := isZipFile(file)
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("reading data file: %w", err)
+	}
+	if !isZip {
+		archive.ReadCloser = io.NopCloser(file)
+		archive.name = filepath.Base(path)
+		return archive, nil
+	}
+
+	if err := archive.openZip(file, archive.Size, c.dataFilePattern()); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// OpenReader opens a data stream such as standard input for reading using
+// DefaultClient.
+func OpenReader(r io.Reader) (*Archive, error) {
+	return DefaultClient.OpenReader(r)
+}
+
+// OpenReader opens a data stream such as standard input for reading. A zip
+// archive is detected by its leading bytes and spooled to a temporary file,
+// since extracting it needs random access; its first data file is opened
+// and Close removes the file. Archives larger than MaxStreamSize are
+// rejected. Any other stream is read as an extracted data file without
+// buffering it, unless Verify spools it first.
+func (c *Client) OpenReader(r io.Reader) (*Archive, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading data stream: %w", err)
+	}
+	if !hasZipSignature(header) {
+		archive := &Archive{streamLimit: c.maxStreamSize()}
+		archive.hashing = newHashingReader(br, &archive.Size)
+		archive.ReadCloser = io.NopCloser(archive.hashing)
+		return archive, nil
+	}
+
+	archive := &Archive{limit: c.maxExtractedSize()}
+	if err := archive.spool(br, "knmi-*.zip", "zip archive", c.maxStreamSize()); err != nil {
+		return nil, err
+	}
+	if err := archive.openZip(archive.file, archive.Size, c.dataFilePattern()); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// spool copies the stream r, described by what in errors, to a temporary
+// file named after pattern and hashes it on the way. Streams larger than
+// limit bytes are rejected with ErrTooLarge; a limit of zero or less
+// disables the check. Close removes the file.
+func (a *Archive) spool(r io.Reader, pattern, what string, limit int64) error {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	discard := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	// Read one byte past the limit to detect a stream that exceeds it
+	src := r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), src)
+	if err != nil {
+		discard()
+		return fmt.Errorf("reading data stream: %w", err)
+	}
+	if limit > 0 && size > limit {
+		discard()
+		return fmt.Errorf("%w: %s on the data stream is larger than %d bytes", ErrTooLarge, what, limit)
+	}
+
+	a.file = file
+	a.temporary = true
+	a.Size = size
+	a.sha256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// openZip reads the zip archive r of the given size, opening its first
+// data file matching pattern.
+func (a *Archive) openZip(r io.ReaderAt, size int64, pattern string) error {
+	files, err := zipDataFiles(r, size, pattern)
+	if err != nil {
+		return err
+	}
+	a.pending = files
+	return a.NextFile()
+}
+
+// Name returns the name of the data file being read: its name within a zip
+// archive, or the base name of a local text file. It is empty for a text
+// file read from a stream.
+func (a *Archive) Name() string {
+	return a.name
+}
+
+// NextFile closes the data file being read and opens the next data file of
+// the archive. It returns io.EOF once every data file has been opened.
+func (a *Archive) NextFile() error {
+	if len(a.pending) == 0 {
+		return io.EOF
+	}
+
+	if a.ReadCloser != nil {
+		err := a.ReadCloser.Close()
+		a.ReadCloser = nil
+		if err != nil {
+			return fmt.Errorf("closing file %s in zip: %w", a.name, err)
 		}
 	}
 
-	return nil, fmt.Errorf("no .txt file found in zip archive")
+	f := a.pending[0]
+	a.pending = a.pending[1:]
+	rc, err := openZipFile(f, a.limit)
+	if err != nil {
+		return err
+	}
+	a.ReadCloser = rc
+	a.name = f.Name
+	return nil
+}
+
+// zipSignatures are the byte sequences a zip archive can start with: a
+// local file header, or the end of central directory record of an empty
+// archive.
+var zipSignatures = [][]byte{
+	[]byte("PK\x03\x04"),
+	[]byte("PK\x05\x06"),
+}
+
+// isZipFile reports whether r starts with a zip signature.
+func isZipFile(r io.ReaderAt) (bool, error) {
+	header := make([]byte, 4)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return hasZipSignature(header[:n]), nil
+}
+
+// hasZipSignature reports whether header is a zip signature.
+func hasZipSignature(header []byte) bool {
+	for _, sig := range zipSignatures {
+		if bytes.Equal(header, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFileURL reports whether rawURL refers to a local file ("file://...").
+func IsFileURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "file://")
+}
+
+// FilePath returns the local path of a file URL such as
+// "file:///data/etmgeg_260.zip".
+func FilePath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL %q: %w", rawURL, err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("invalid file URL %q: host must be empty or localhost", rawURL)
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("invalid file URL %q: path is empty", rawURL)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// FileURL returns the file URL of a local path. Characters such as "#",
+// "?" and "%" are escaped, but braces are kept so placeholders such as
+// {station} can still be replaced.
+func FileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolving path %s: %w", path, err)
+	}
+	u := &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	return braceUnescaper.Replace(u.String()), nil
+}
+
+// braceUnescaper restores the braces escaped in a URL path.
+var braceUnescaper = strings.NewReplacer("%7B", "{", "%7D", "}")
+
+// SaveToCache stores a copy of a downloaded archive in the cache of the
+// client that downloaded it. Call it once the archive has been verified,
+// so a corrupt or tampered download never becomes the cached copy, and
+// before Close. It does nothing if the client has no cache or the archive
+// was not downloaded.
+func (a *Archive) SaveToCache() error {
+	if a.cache == nil {
+		return nil
+	}
+	_, err := a.cache.Store(a.cacheURL, io.NewSectionReader(a.file, 0, a.Size), time.Now())
+	return err
+}
+
+// Close closes the data file and removes the archive if it was downloaded.
+func (a *Archive) Close() error {
+	var err error
+	if a.ReadCloser != nil {
+		err = a.ReadCloser.Close()
+	}
+	if a.file == nil {
+		return err
+	}
+	a.file.Close()
+	if a.temporary {
+		if rmErr := os.Remove(a.file.Name()); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}
+	return err
 }
@@ -0,0 +1,240 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenDataScheme is the URL scheme of files on the KNMI Open Data API, as in
+// "opendata://<dataset>/<version>/<filename>".
+const OpenDataScheme = "opendata"
+
+// openDataPageSize is the number of files requested per listing page.
+const openDataPageSize = 500
+
+// ErrUnauthorized is returned when the Open Data API rejects the API key.
+var ErrUnauthorized = errors.New("KNMI Open Data API rejected the API key")
+
+// OpenDataFile describes a file of an Open Data API dataset.
+type OpenDataFile struct {
+	Filename     string    `json:"filename"`
+	Size         int64     `json:"size"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// OpenDataRef identifies a file of an Open Data API dataset. The filename
+// may be a glob pattern, which selects the most recently modified match.
+type OpenDataRef struct {
+	Dataset  string
+	Version  string
+	Filename string
+}
+
+// String returns the opendata:// URL of the file.
+func (r OpenDataRef) String() string {
+	return OpenDataScheme + "://" + r.Dataset + "/" + r.Version + "/" + r.Filename
+}
+
+// IsOpenDataURL reports whether rawURL refers to a file on the Open Data
+// API ("opendata://...").
+func IsOpenDataURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), OpenDataScheme+"://")
+}
+
+// ParseOpenDataURL parses an opendata:// URL such as
+// "opendata://etmaalgegevensKNMIstations/1/etmgeg_260.zip".
+func ParseOpenDataURL(rawURL string) (OpenDataRef, error) {
+	if !IsOpenDataURL(rawURL) {
+		return OpenDataRef{}, fmt.Errorf("invalid Open Data URL %q: scheme must be %s://", rawURL, OpenDataScheme)
+	}
+
+	parts := strings.SplitN(rawURL[len(OpenDataScheme)+3:], "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return OpenDataRef{}, fmt.Errorf("invalid Open Data URL %q: expected %s://<dataset>/<version>/<filename>", rawURL, OpenDataScheme)
+	}
+	return OpenDataRef{Dataset: parts[0], Version: parts[1], Filename: parts[2]}, nil
+}
+
+// OpenDataClient lists and downloads the files of the KNMI Open Data API
+// (https://api.dataplatform.knmi.nl). Files are downloaded from the
+// temporary URL the API hands out for them.
+type OpenDataClient struct {
+	// BaseURL is the base URL of the API, e.g.
+	// "https://api.dataplatform.knmi.nl/open-data/v1".
+	BaseURL string
+
+	// APIKey authenticates requests to the API.
+	APIKey string
+
+	// Client makes the requests, retrying them and caching downloaded
+	// archives as configured. DefaultClient is used if nil.
+	Client *Client
+}
+
+// NewOpenDataClient creates a client for the API at baseURL that makes its
+// requests with client.
+func NewOpenDataClient(baseURL, apiKey string, client *Client) *OpenDataClient {
+	return &OpenDataClient{BaseURL: baseURL, APIKey: apiKey, Client: client}
+}
+
+// openDataFileList is a page of a dataset's file listing.
+type openDataFileList struct {
+	Files         []OpenDataFile `json:"files"`
+	IsTruncated   bool           `json:"isTruncated"`
+	NextPageToken string         `json:"nextPageToken"`
+}
+
+// ListFiles returns every file of a dataset version.
+func (o *OpenDataClient) ListFiles(ctx context.Context, dataset, version string) ([]OpenDataFile, error) {
+	var files []OpenDataFile
+	token := ""
+	for {
+		query := url.Values{"maxKeys": {fmt.Sprint(openDataPageSize)}}
+		if token != "" {
+			query.Set("nextPageToken", token)
+		}
+
+		var page openDataFileList
+		endpoint := o.datasetURL(dataset, version) + "/files?" + query.Encode()
+		if err := o.getJSON(ctx, endpoint, &page); err != nil {
+			return nil, fmt.Errorf("listing files of %s/%s: %w", dataset, version, err)
+		}
+		files = append(files, page.Files...)
+
+		if !page.IsTruncated || page.NextPageToken == "" {
+			return files, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// DownloadURL returns the temporary URL a file can be downloaded from.
+func (o *OpenDataClient) DownloadURL(ctx context.Context, dataset, version, filename string) (string, error) {
+	var response struct {
+		TemporaryDownloadURL string `json:"temporaryDownloadUrl"`
+	}
+	endpoint := o.datasetURL(dataset, version) + "/files/" + url.PathEscape(filename) + "/url"
+	if err := o.getJSON(ctx, endpoint, &response); err != nil {
+		return "", fmt.Errorf("getting download URL of %s: %w", filename, err)
+	}
+	if response.TemporaryDownloadURL == "" {
+		return "", fmt.Errorf("getting download URL of %s: response has no temporaryDownloadUrl", filename)
+	}
+	return response.TemporaryDownloadURL, nil
+}
+
+// Download fetches the file at an opendata:// URL.
+func (o *OpenDataClient) Download(ctx context.Context, rawURL string) ([]byte, error) {
+	downloadURL, err := o.resolve(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return o.client().Download(ctx, downloadURL)
+}
+
+// Open downloads the zip archive at an opendata:// URL and opens its first
+// data file for reading. Close removes the downloaded archive.
+func (o *OpenDataClient) Open(ctx context.Context, rawURL string) (*Archive, error) {
+	return o.OpenIfModified(ctx, rawURL, Validators{})
+}
+
+// OpenIfModified is like Open, but makes a conditional request with the
+// validators of a previous download, returning ErrNotModified if the file
+// has not changed. Archives are cached under their opendata:// URL, since
+// the download URL changes with every request.
+func (o *OpenDataClient) OpenIfModified(ctx context.Context, rawURL string, since Validators) (*Archive, error) {
+	downloadURL, err := o.resolve(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return o.client().openDownload(ctx, downloadURL, rawURL, since)
+}
+
+// resolve returns the temporary download URL of the file at an opendata://
+// URL, looking up the newest match if its filename is a glob pattern.
+func (o *OpenDataClient) resolve(ctx context.Context, rawURL string) (string, error) {
+	ref, err := ParseOpenDataURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.ContainsAny(ref.Filename, "*?[") {
+		ref.Filename, err = o.latestMatch(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return o.DownloadURL(ctx, ref.Dataset, ref.Version, ref.Filename)
+}
+
+// latestMatch returns the most recently modified file of the dataset whose
+// name matches the glob pattern in ref.Filename.
+func (o *OpenDataClient) latestMatch(ctx context.Context, ref OpenDataRef) (string, error) {
+	if _, err := path.Match(ref.Filename, ""); err != nil {
+		return "", fmt.Errorf("invalid file pattern %q: %w", ref.Filename, err)
+	}
+
+	files, err := o.ListFiles(ctx, ref.Dataset, ref.Version)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []OpenDataFile
+	for _, f := range files {
+		if ok, _ := path.Match(ref.Filename, f.Filename); ok {
+			matches = append(matches, f)
+		}
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no file matching %q in %s/%s", ref.Filename, ref.Dataset, ref.Version)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].LastModified.Equal(matches[j].LastModified) {
+			return matches[i].LastModified.Before(matches[j].LastModified)
+		}
+		return matches[i].Filename < matches[j].Filename
+	})
+	return matches[len(matches)-1].Filename, nil
+}
+
+// getJSON requests an API endpoint and decodes its JSON response into v.
+func (o *OpenDataClient) getJSON(ctx context.Context, endpoint string, v any) error {
+	buf := &bufferSink{}
+	d := &download{url: endpoint, dst: buf, header: http.Header{"Authorization": {o.APIKey}}}
+	if err := o.client().run(ctx, d); err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden) {
+			return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+		return err
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return fmt.Errorf("decoding API response: %w", err)
+	}
+	return nil
+}
+
+// datasetURL returns the API URL of a dataset version.
+func (o *OpenDataClient) datasetURL(dataset, version string) string {
+	return strings.TrimSuffix(o.BaseURL, "/") + "/datasets/" + url.PathEscape(dataset) + "/versions/" + url.PathEscape(version)
+}
+
+// client returns the client to make requests with.
+func (o *OpenDataClient) client() *Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return DefaultClient
+}
@@ -0,0 +1,304 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultSourceName is the name of the source serving the KNMI CDN.
+const DefaultSourceName = "cdn"
+
+// StdinURL is the URL of a data file read from standard input.
+const StdinURL = "-"
+
+// ErrOffline is returned when a remote source is asked to download a file,
+// such as a checksum file, in offline mode. Only archives are cached.
+var ErrOffline = errors.New("cannot download in offline mode")
+
+// Source fetches KNMI data files from one kind of location, such as the
+// KNMI CDN, the local file system or the Open Data API. Sources are
+// registered with RegisterSource and picked by the scheme of a URL.
+type Source interface {
+	// Fetch opens the data file at url. The returned archive describes
+	// the file fetched: its size, SHA-256 hash and validators. Remote
+	// sources make the request conditional on since, returning
+	// ErrNotModified if the file has not changed.
+	Fetch(ctx context.Context, url string, since Validators) (*Archive, error)
+
+	// Download fetches a small file, such as a checksum file, whole.
+	Download(ctx context.Context, url string) ([]byte, error)
+}
+
+// Locator is implemented by sources that serve the files of the KNMI CDN
+// under URLs of their own.
+type Locator interface {
+	// Locate returns the URL template under which the source serves the
+	// files of a KNMI CDN URL template. Placeholders are kept.
+	Locate(template string) string
+}
+
+// SourceOptions configures the sources created by NewSource.
+type SourceOptions struct {
+	// Client downloads remote files and caches them, and opens the
+	// archives of every source. DefaultClient is used if nil.
+	Client *Client
+
+	// Offline makes remote sources open the newest copy of a file in the
+	// client's cache instead of downloading it. Their Download fails with
+	// ErrOffline.
+	Offline bool
+
+	// Stdin is read by the standard input source. os.Stdin is used if nil.
+	Stdin io.Reader
+
+	// APIKey authenticates requests to the KNMI Open Data API.
+	APIKey string
+
+	// OpenDataURL is the base URL of the KNMI Open Data API.
+	OpenDataURL string
+
+	// OpenDataDataset and OpenDataVersion select the Open Data API
+	// dataset the files of the KNMI CDN are looked up in.
+	OpenDataDataset string
+	OpenDataVersion string
+
+	// Logf, if set, reports the progress of fetching a file.
+	Logf func(format string, args ...any)
+}
+
+// SourceFactory creates a source configured by opts.
+type SourceFactory func(opts SourceOptions) (Source, error)
+
+// registeredSource is a source registered with RegisterSource.
+type registeredSource struct {
+	name    string
+	schemes []string
+	factory SourceFactory
+}
+
+// sources are the registered sources, in registration order.
+var sources []registeredSource
+
+// RegisterSource registers a source under a name and the URL schemes it
+// fetches, such as "https" or "opendata". The scheme of StdinURL is
+// "stdin". A later registration of a name or scheme replaces an earlier one.
+func RegisterSource(name string, schemes []string, factory SourceFactory) {
+	for i := range sources {
+		if sources[i].name == name {
+			sources = append(sources[:i], sources[i+1:]...)
+			break
+		}
+	}
+	sources = append(sources, registeredSource{name: name, schemes: schemes, factory: factory})
+}
+
+// SourceNames returns the names of the registered sources.
+func SourceNames() []string {
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		names[i] = s.name
+	}
+	return names
+}
+
+// NewSource creates the source fetching url, picked by its scheme.
+func NewSource(url string, opts SourceOptions) (Source, error) {
+	scheme := urlScheme(url)
+	for i := len(sources) - 1; i >= 0; i-- {
+		for _, s := range sources[i].schemes {
+			if s == scheme {
+				return sources[i].factory(opts)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no source for URL %q", url)
+}
+
+// NewNamedSource creates the source registered under name.
+func NewNamedSource(name string, opts SourceOptions) (Source, error) {
+	for _, s := range sources {
+		if s.name == name {
+			return s.factory(opts)
+		}
+	}
+	return nil, fmt.Errorf("unknown source %q (expected one of %s)", name, strings.Join(SourceNames(), ", "))
+}
+
+// urlScheme returns the lower-cased scheme of url, "stdin" for StdinURL.
+func urlScheme(url string) string {
+	if url == StdinURL {
+		return "stdin"
+	}
+	scheme, _, ok := strings.Cut(url, "://")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
+
+func init() {
+	RegisterSource(DefaultSourceName, []string{"http", "https"}, newHTTPSource)
+	RegisterSource("file", []string{"file"}, newFileSource)
+	RegisterSource("stdin", []string{"stdin"}, newStdinSource)
+	RegisterSource("opendata", []string{OpenDataScheme}, newOpenDataSource)
+}
+
+// logf reports progress through opts.Logf, if set.
+func (opts SourceOptions) logf(format string, args ...any) {
+	if opts.Logf != nil {
+		opts.Logf(format, args...)
+	}
+}
+
+// client returns the client to download with.
+func (opts SourceOptions) client() *Client {
+	if opts.Client != nil {
+		return opts.Client
+	}
+	return DefaultClient
+}
+
+// openCached opens the newest cached copy of url in offline mode.
+func (opts SourceOptions) openCached(url string) (*Archive, error) {
+	cache := opts.client().Cache
+	if cache == nil {
+		return nil, fmt.Errorf("offline mode requires an archive cache")
+	}
+	path, err := cache.Latest(url)
+	if err != nil {
+		return nil, err
+	}
+	opts.logf("Using cached archive %s", path)
+	return opts.client().OpenFile(path)
+}
+
+// httpSource downloads archives over HTTP, e.g. from the KNMI CDN.
+type httpSource struct {
+	opts SourceOptions
+}
+
+func newHTTPSource(opts SourceOptions) (Source, error) {
+	return &httpSource{opts: opts}, nil
+}
+
+func (s *httpSource) Fetch(ctx context.Context, url string, since Validators) (*Archive, error) {
+	if s.opts.Offline {
+		return s.opts.openCached(url)
+	}
+	s.opts.logf("Downloading from %s...", url)
+	archive, err := s.opts.client().OpenIfModified(ctx, url, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download data: %w", err)
+	}
+	s.opts.logf("Downloaded %.2f MB", float64(archive.Size)/(1024*1024))
+	return archive, nil
+}
+
+func (s *httpSource) Download(ctx context.Context, url string) ([]byte, error) {
+	if s.opts.Offline {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, url)
+	}
+	return s.opts.client().Download(ctx, url)
+}
+
+// fileSource reads local files addressed by file:// URLs.
+type fileSource struct {
+	opts SourceOptions
+}
+
+func newFileSource(opts SourceOptions) (Source, error) {
+	return &fileSource{opts: opts}, nil
+}
+
+func (s *fileSource) Fetch(ctx context.Context, url string, since Validators) (*Archive, error) {
+	path, err := FilePath(url)
+	if err != nil {
+		return nil, err
+	}
+	s.opts.logf("Reading %s...", path)
+	return s.opts.client().OpenFile(path)
+}
+
+func (s *fileSource) Download(ctx context.Context, url string) ([]byte, error) {
+	path, err := FilePath(url)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading data file: %w", err)
+	}
+	return data, nil
+}
+
+// stdinSource reads a data file from standard input.
+type stdinSource struct {
+	opts SourceOptions
+}
+
+func newStdinSource(opts SourceOptions) (Source, error) {
+	return &stdinSource{opts: opts}, nil
+}
+
+func (s *stdinSource) Fetch(ctx context.Context, url string, since Validators) (*Archive, error) {
+	s.opts.logf("Reading standard input...")
+	if s.opts.Stdin != nil {
+		return s.opts.client().OpenReader(s.opts.Stdin)
+	}
+	return s.opts.client().OpenReader(os.Stdin)
+}
+
+func (s *stdinSource) Download(ctx context.Context, url string) ([]byte, error) {
+	return nil, fmt.Errorf("cannot download %s from standard input", url)
+}
+
+// openDataSource downloads archives from the KNMI Open Data API.
+type openDataSource struct {
+	opts   SourceOptions
+	client *OpenDataClient
+}
+
+func newOpenDataSource(opts SourceOptions) (Source, error) {
+	if opts.APIKey == "" && !opts.Offline {
+		return nil, fmt.Errorf("the KNMI Open Data API requires an API key (set KNMI_API_KEY)")
+	}
+	return &openDataSource{
+		opts:   opts,
+		client: NewOpenDataClient(opts.OpenDataURL, opts.APIKey, opts.client()),
+	}, nil
+}
+
+func (s *openDataSource) Fetch(ctx context.Context, url string, since Validators) (*Archive, error) {
+	if s.opts.Offline {
+		return s.opts.openCached(url)
+	}
+	s.opts.logf("Downloading %s from the KNMI Open Data API...", url)
+	archive, err := s.client.OpenIfModified(ctx, url, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download data: %w", err)
+	}
+	s.opts.logf("Downloaded %.2f MB", float64(archive.Size)/(1024*1024))
+	return archive, nil
+}
+
+func (s *openDataSource) Download(ctx context.Context, url string) ([]byte, error) {
+	if s.opts.Offline {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, url)
+	}
+	return s.client.Download(ctx, url)
+}
+
+// Locate looks up the files of the KNMI CDN by name in the configured
+// Open Data API dataset.
+func (s *openDataSource) Locate(template string) string {
+	return OpenDataRef{
+		Dataset:  s.opts.OpenDataDataset,
+		Version:  s.opts.OpenDataVersion,
+		Filename: path.Base(template),
+	}.String()
+}
@@ -0,0 +1,157 @@
+package fetch
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxExtractedSize is the default limit on the size of a data file
+// extracted from a zip archive (1 GiB). KNMI data files are far smaller.
+const DefaultMaxExtractedSize = 1 << 30
+
+// DefaultMaxStreamSize is the default limit on the size of a zip archive
+// read from a stream such as standard input, or of a data file streamed
+// from one that is spooled for verification (2 GiB).
+const DefaultMaxStreamSize = 2 << 30
+
+// ErrChecksumMismatch is returned when an archive does not match its
+// expected SHA-256 hash.
+var ErrChecksumMismatch = errors.New("SHA-256 checksum mismatch")
+
+// ErrTooLarge is returned when a data file extracts to more than the
+// client's MaxExtractedSize bytes, or a stream spooled to a temporary file
+// is larger than its MaxStreamSize.
+var ErrTooLarge = errors.New("extracted data exceeds size limit")
+
+// checksumRegex matches a hex-encoded SHA-256 hash.
+var checksumRegex = regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`)
+
+// ParseChecksum extracts the SHA-256 hash from the content of a checksum
+// file, as written by "sha256sum" ("<hash>  <file>") or "shasum -a 256
+// --tag" ("SHA256 (<file>) = <hash>").
+func ParseChecksum(data []byte) (string, error) {
+	match := checksumRegex.Find(data)
+	if match == nil {
+		return "", fmt.Errorf("no SHA-256 checksum found")
+	}
+	return strings.ToLower(string(match)), nil
+}
+
+// SHA256 returns the hex-encoded SHA-256 hash of the archive as it was
+// downloaded or opened. For a data file streamed from a reader the hash is
+// only complete once the file has been read to the end.
+func (a *Archive) SHA256() string {
+	if a.hashing != nil {
+		return hex.EncodeToString(a.hashing.hash.Sum(nil))
+	}
+	return a.sha256
+}
+
+// Verify checks the archive against an expected hex-encoded SHA-256 hash,
+// returning an error wrapping ErrChecksumMismatch if they differ. A data
+// file streamed from a reader is first spooled to a temporary file, up to
+// the client's MaxStreamSize, so it is checked before any of it is parsed.
+// Verify must then be called before the data file is read.
+func (a *Archive) Verify(expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if a.hashing != nil {
+		if err := a.spoolStream(); err != nil {
+			return err
+		}
+	}
+	return checkSum(a.sha256, expected)
+}
+
+// spoolStream replaces the data file streamed from a reader with a copy
+// spooled to a temporary file, whose hash is then known.
+func (a *Archive) spoolStream() error {
+	if a.Size > 0 {
+		return fmt.Errorf("cannot verify a data stream that has already been read")
+	}
+	if err := a.spool(a.hashing.r, "knmi-*.txt", "data file", a.streamLimit); err != nil {
+		return err
+	}
+	if _, err := a.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewinding spooled data stream: %w", err)
+	}
+	a.ReadCloser = io.NopCloser(a.file)
+	a.hashing = nil
+	return nil
+}
+
+// checkSum compares an actual hash with the expected one.
+func checkSum(actual, expected string) error {
+	if actual != expected {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, actual)
+	}
+	return nil
+}
+
+// hashReader returns the hex-encoded SHA-256 hash of the first size bytes of r.
+func hashReader(r io.ReaderAt, size int64) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return "", fmt.Errorf("hashing archive: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashingReader hashes a stream as it is read, counting the bytes read.
+type hashingReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size *int64
+}
+
+// newHashingReader wraps r to hash everything read from it, adding the
+// number of bytes read to *size.
+func newHashingReader(r io.Reader, size *int64) *hashingReader {
+	return &hashingReader{r: r, hash: sha256.New(), size: size}
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	*h.size += int64(n)
+	return n, err
+}
+
+// zipEntryReader reads a file from a zip archive, enforcing the extracted
+// size limit and describing corrupt or truncated data clearly.
+type zipEntryReader struct {
+	rc    io.ReadCloser
+	name  string
+	read  int64
+	limit int64
+}
+
+func (z *zipEntryReader) Read(p []byte) (int, error) {
+	n, err := z.rc.Read(p)
+	z.read += int64(n)
+	if z.limit > 0 && z.read > z.limit {
+		return n, fmt.Errorf("%w: %s extracts to more than %d bytes", ErrTooLarge, z.name, z.limit)
+	}
+
+	switch {
+	case errors.Is(err, zip.ErrChecksum):
+		return n, fmt.Errorf("file %s in zip is corrupt (CRC-32 mismatch): %w", z.name, err)
+	case errors.Is(err, zip.ErrFormat):
+		return n, fmt.Errorf("file %s in zip is corrupt (size does not match its header): %w", z.name, err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return n, fmt.Errorf("file %s in zip is truncated: %w", z.name, err)
+	case err != nil && err != io.EOF:
+		return n, fmt.Errorf("reading file %s from zip: %w", z.name, err)
+	}
+	return n, err
+}
+
+func (z *zipEntryReader) Close() error {
+	return z.rc.Close()
+}
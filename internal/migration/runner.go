@@ -2,6 +2,7 @@
 package migration
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -127,11 +128,13 @@ func NewRunner(db *sql.DB, logFn LogFunc) *Runner {
 }
 
 // Run discovers and applies pending migrations from the given directory.
-func (r *Runner) Run(dir string) (*Result, error) {
+// Each migration is applied in its own transaction; if ctx is cancelled, the
+// migration being applied is rolled back and the ones before it are kept.
+func (r *Runner) Run(ctx context.Context, dir string) (*Result, error) {
 	start := time.Now()
 
 	// Ensure migrations table exists
-	if err := r.tracker.EnsureTable(); err != nil {
+	if err := r.tracker.EnsureTable(ctx); err != nil {
 		return nil, fmt.Errorf("ensuring migrations table: %w", err)
 	}
 
@@ -148,7 +151,7 @@ func (r *Runner) Run(dir string) (*Result, error) {
 	}
 
 	// Get already applied migrations
-	applied, err := r.tracker.Applied()
+	applied, err := r.tracker.Applied(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting applied migrations: %w", err)
 	}
@@ -174,7 +177,7 @@ func (r *Runner) Run(dir string) (*Result, error) {
 	// Apply each pending migration
 	result := &Result{}
 	for _, m := range pending {
-		if err := r.applyMigration(m); err != nil {
+		if err := r.applyMigration(ctx, m); err != nil {
 			return result, fmt.Errorf("migration %s failed: %w", m.Filename, err)
 		}
 		result.Applied = append(result.Applied, m.Filename)
@@ -187,23 +190,23 @@ func (r *Runner) Run(dir string) (*Result, error) {
 }
 
 // applyMigration applies a single migration within a transaction.
-func (r *Runner) applyMigration(m Migration) error {
+func (r *Runner) applyMigration(ctx context.Context, m Migration) error {
 	r.log("Applying %s...", m.Filename)
 	migrationStart := time.Now()
 
-	tx, err := r.db.Begin()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	// Execute the migration SQL
-	if _, err := tx.Exec(m.Content); err != nil {
+	if _, err := tx.ExecContext(ctx, m.Content); err != nil {
 		return fmt.Errorf("executing SQL: %w", err)
 	}
 
-	// Record the migration
-	if err := r.tracker.Record(m.Version, m.Filename); err != nil {
+	// Record the migration as part of the same transaction
+	if err := record(ctx, tx, m.Version, m.Filename); err != nil {
 		return fmt.Errorf("recording migration: %w", err)
 	}
 
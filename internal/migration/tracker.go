@@ -2,6 +2,7 @@
 package migration
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -18,7 +19,7 @@ func NewTracker(db *sql.DB) *Tracker {
 }
 
 // EnsureTable creates the migrations tracking table if it doesn't exist.
-func (t *Tracker) EnsureTable() error {
+func (t *Tracker) EnsureTable(ctx context.Context) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS migrations (
 			id SERIAL PRIMARY KEY,
@@ -27,7 +28,7 @@ func (t *Tracker) EnsureTable() error {
 			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 		)
 	`
-	_, err := t.db.Exec(query)
+	_, err := t.db.ExecContext(ctx, query)
 	if err != nil {
 		return fmt.Errorf("creating migrations table: %w", err)
 	}
@@ -35,8 +36,8 @@ func (t *Tracker) EnsureTable() error {
 }
 
 // Applied returns a map of version numbers that have already been applied.
-func (t *Tracker) Applied() (map[int]bool, error) {
-	rows, err := t.db.Query("SELECT version FROM migrations")
+func (t *Tracker) Applied(ctx context.Context) (map[int]bool, error) {
+	rows, err := t.db.QueryContext(ctx, "SELECT version FROM migrations")
 	if err != nil {
 		return nil, fmt.Errorf("querying applied migrations: %w", err)
 	}
@@ -59,9 +60,21 @@ func (t *Tracker) Applied() (map[int]bool, error) {
 }
 
 // Record marks a migration as applied.
-func (t *Tracker) Record(version int, filename string) error {
+func (t *Tracker) Record(ctx context.Context, version int, filename string) error {
+	return record(ctx, t.db, version, filename)
+}
+
+// execer executes statements, either directly on the database or within a
+// transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// record marks a migration as applied using db, so a migration can be
+// recorded in the same transaction that applies it.
+func record(ctx context.Context, db execer, version int, filename string) error {
 	query := `INSERT INTO migrations (version, filename, applied_at) VALUES ($1, $2, $3)`
-	_, err := t.db.Exec(query, version, filename, time.Now())
+	_, err := db.ExecContext(ctx, query, version, filename, time.Now())
 	if err != nil {
 		return fmt.Errorf("recording migration: %w", err)
 	}
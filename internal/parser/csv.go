@@ -2,7 +2,6 @@
 package parser
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"strconv"
@@ -10,9 +9,15 @@ import (
 	"time"
 )
 
-// ExpectedColumns is the number of columns in the KNMI data file.
+// ExpectedColumns is the number of columns in a complete KNMI daily data file.
 const ExpectedColumns = 41
 
+// TraceValue is the value KNMI uses in the precipitation columns (RH, RHX
+// and DR) for a trace amount: less than 0.05 mm. Parsed records store such
+// values as 0 with the matching trace flag set, so sums and averages are not
+// skewed by the sentinel.
+const TraceValue = -1
+
 // WeatherRecord represents a single weather observation.
 type WeatherRecord struct {
 	StationID int
@@ -56,154 +61,154 @@ type WeatherRecord struct {
 	UN        *int
 	UNH       *int
 	EV24      *int
-}
-
-// ParseCSV parses KNMI weather data from a reader.
-func ParseCSV(r io.Reader) ([]WeatherRecord, error) {
-	var records []WeatherRecord
-
-	scanner := bufio.NewScanner(r)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Skip header/description lines - data lines start with station ID (digits)
-		// KNMI files have description text before the actual data
-		if len(line) == 0 || (line[0] < '0' || line[0] > '9') {
-			continue
-		}
-
-		record, err := parseLine(line, lineNum)
-		if err != nil {
-			return nil, err
-		}
-
-		records = append(records, *record)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("reading input: %w", err)
-	}
+	// DRTrace, RHTrace and RHXTrace report that KNMI recorded a trace
+	// amount (TraceValue) for DR, RH and RHX; the field itself is then 0.
+	DRTrace  bool
+	RHTrace  bool
+	RHXTrace bool
+}
 
-	return records, nil
+// DefaultColumns is the column layout of KNMI daily data files. It is used
+// for files that do not contain a "# STN,YYYYMMDD,..." header line.
+var DefaultColumns = []string{
+	"STN", "YYYYMMDD", "DDVEC", "FHVEC", "FG", "FHX", "FHXH", "FHN", "FHNH", "FXX", "FXXH",
+	"TG", "TN", "TNH", "TX", "TXH", "T10N", "T10NH", "SQ", "SP", "Q",
+	"DR", "RH", "RHX", "RHXH", "PG", "PX", "PXH", "PN", "PNH",
+	"VVN", "VVNH", "VVX", "VVXH", "NG", "UG", "UX", "UXH", "UN", "UNH", "EV24",
 }
 
-// parseLine parses a single data line.
-func parseLine(line string, lineNum int) (*WeatherRecord, error) {
-	fields := strings.Split(line, ",")
+// weatherFields maps KNMI column names to the WeatherRecord field they fill.
+var weatherFields = map[string]func(*WeatherRecord) **int{
+	"DDVEC": func(r *WeatherRecord) **int { return &r.DDVEC },
+	"FHVEC": func(r *WeatherRecord) **int { return &r.FHVEC },
+	"FG":    func(r *WeatherRecord) **int { return &r.FG },
+	"FHX":   func(r *WeatherRecord) **int { return &r.FHX },
+	"FHXH":  func(r *WeatherRecord) **int { return &r.FHXH },
+	"FHN":   func(r *WeatherRecord) **int { return &r.FHN },
+	"FHNH":  func(r *WeatherRecord) **int { return &r.FHNH },
+	"FXX":   func(r *WeatherRecord) **int { return &r.FXX },
+	"FXXH":  func(r *WeatherRecord) **int { return &r.FXXH },
+	"TG":    func(r *WeatherRecord) **int { return &r.TG },
+	"TN":    func(r *WeatherRecord) **int { return &r.TN },
+	"TNH":   func(r *WeatherRecord) **int { return &r.TNH },
+	"TX":    func(r *WeatherRecord) **int { return &r.TX },
+	"TXH":   func(r *WeatherRecord) **int { return &r.TXH },
+	"T10N":  func(r *WeatherRecord) **int { return &r.T10N },
+	"T10NH": func(r *WeatherRecord) **int { return &r.T10NH },
+	"SQ":    func(r *WeatherRecord) **int { return &r.SQ },
+	"SP":    func(r *WeatherRecord) **int { return &r.SP },
+	"Q":     func(r *WeatherRecord) **int { return &r.Q },
+	"DR":    func(r *WeatherRecord) **int { return &r.DR },
+	"RH":    func(r *WeatherRecord) **int { return &r.RH },
+	"RHX":   func(r *WeatherRecord) **int { return &r.RHX },
+	"RHXH":  func(r *WeatherRecord) **int { return &r.RHXH },
+	"PG":    func(r *WeatherRecord) **int { return &r.PG },
+	"PX":    func(r *WeatherRecord) **int { return &r.PX },
+	"PXH":   func(r *WeatherRecord) **int { return &r.PXH },
+	"PN":    func(r *WeatherRecord) **int { return &r.PN },
+	"PNH":   func(r *WeatherRecord) **int { return &r.PNH },
+	"VVN":   func(r *WeatherRecord) **int { return &r.VVN },
+	"VVNH":  func(r *WeatherRecord) **int { return &r.VVNH },
+	"VVX":   func(r *WeatherRecord) **int { return &r.VVX },
+	"VVXH":  func(r *WeatherRecord) **int { return &r.VVXH },
+	"NG":    func(r *WeatherRecord) **int { return &r.NG },
+	"UG":    func(r *WeatherRecord) **int { return &r.UG },
+	"UX":    func(r *WeatherRecord) **int { return &r.UX },
+	"UXH":   func(r *WeatherRecord) **int { return &r.UXH },
+	"UN":    func(r *WeatherRecord) **int { return &r.UN },
+	"UNH":   func(r *WeatherRecord) **int { return &r.UNH },
+	"EV24":  func(r *WeatherRecord) **int { return &r.EV24 },
+}
 
-	if len(fields) != ExpectedColumns {
-		return nil, fmt.Errorf("line %d: expected %d columns, got %d", lineNum, ExpectedColumns, len(fields))
+// Value returns the raw value of a measurement column, such as "TG", or nil
+// if the value is missing or the column is unknown.
+func (r *WeatherRecord) Value(column string) *int {
+	field, ok := weatherFields[column]
+	if !ok {
+		return nil
 	}
+	return *field(r)
+}
 
-	// Parse station ID (required)
-	stationID, err := parseRequiredInt(fields[0], "station_id", lineNum)
-	if err != nil {
-		return nil, err
-	}
+// weatherSchema describes how KNMI daily data columns fill a WeatherRecord.
+var weatherSchema = &schema[WeatherRecord]{
+	defaultColumns: DefaultColumns,
+	keys: map[string]keyField[WeatherRecord]{
+		"STN": {name: "station_id", field: func(r *WeatherRecord) *int { return &r.StationID }},
+	},
+	date:   func(r *WeatherRecord) *time.Time { return &r.Date },
+	fields: weatherFields,
+	traces: map[string]func(*WeatherRecord) *bool{
+		"DR":  func(r *WeatherRecord) *bool { return &r.DRTrace },
+		"RH":  func(r *WeatherRecord) *bool { return &r.RHTrace },
+		"RHX": func(r *WeatherRecord) *bool { return &r.RHXTrace },
+	},
+}
 
-	// Parse date (required)
-	date, err := parseDate(fields[1], lineNum)
+// ParseCSV parses KNMI weather data from a reader.
+func ParseCSV(r io.Reader) ([]WeatherRecord, error) {
+	result, err := Parse(r)
 	if err != nil {
 		return nil, err
 	}
+	return result.Records, nil
+}
 
-	record := &WeatherRecord{
-		StationID: stationID,
-		Date:      date,
-	}
+// Parse parses KNMI weather data from a reader.
+// Columns are mapped by name using the file's "# STN,YYYYMMDD,..." header
+// line, so files with a subset of the columns or extra columns are supported.
+// Files without a header are parsed using DefaultColumns.
+func Parse(r io.Reader) (*Result[WeatherRecord], error) {
+	return parseRecords(r, weatherSchema)
+}
 
-	// Parse optional integer fields
-	record.DDVEC = parseOptionalInt(fields[2])
-	record.FHVEC = parseOptionalInt(fields[3])
-	record.FG = parseOptionalInt(fields[4])
-	record.FHX = parseOptionalInt(fields[5])
-	record.FHXH = parseOptionalInt(fields[6])
-	record.FHN = parseOptionalInt(fields[7])
-	record.FHNH = parseOptionalInt(fields[8])
-	record.FXX = parseOptionalInt(fields[9])
-	record.FXXH = parseOptionalInt(fields[10])
-	record.TG = parseOptionalInt(fields[11])
-	record.TN = parseOptionalInt(fields[12])
-	record.TNH = parseOptionalInt(fields[13])
-	record.TX = parseOptionalInt(fields[14])
-	record.TXH = parseOptionalInt(fields[15])
-	record.T10N = parseOptionalInt(fields[16])
-	record.T10NH = parseOptionalInt(fields[17])
-	record.SQ = parseOptionalInt(fields[18])
-	record.SP = parseOptionalInt(fields[19])
-	record.Q = parseOptionalInt(fields[20])
-	record.DR = parseOptionalInt(fields[21])
-	record.RH = parseOptionalInt(fields[22])
-	record.RHX = parseOptionalInt(fields[23])
-	record.RHXH = parseOptionalInt(fields[24])
-	record.PG = parseOptionalInt(fields[25])
-	record.PX = parseOptionalInt(fields[26])
-	record.PXH = parseOptionalInt(fields[27])
-	record.PN = parseOptionalInt(fields[28])
-	record.PNH = parseOptionalInt(fields[29])
-	record.VVN = parseOptionalInt(fields[30])
-	record.VVNH = parseOptionalInt(fields[31])
-	record.VVX = parseOptionalInt(fields[32])
-	record.VVXH = parseOptionalInt(fields[33])
-	record.NG = parseOptionalInt(fields[34])
-	record.UG = parseOptionalInt(fields[35])
-	record.UX = parseOptionalInt(fields[36])
-	record.UXH = parseOptionalInt(fields[37])
-	record.UN = parseOptionalInt(fields[38])
-	record.UNH = parseOptionalInt(fields[39])
-	record.EV24 = parseOptionalInt(fields[40])
-
-	return record, nil
+// NewReader returns a Reader that streams KNMI weather data (etmgeg files)
+// from r one record at a time.
+func NewReader(r io.Reader) *Reader[WeatherRecord] {
+	return newReader(r, weatherSchema)
 }
 
 // parseRequiredInt parses a required integer field.
-func parseRequiredInt(s, name string, lineNum int) (int, error) {
+func parseRequiredInt(s, name string) (int, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
-		return 0, fmt.Errorf("line %d: required field %s is empty", lineNum, name)
+		return 0, fmt.Errorf("required field %s is empty", name)
 	}
 
 	v, err := strconv.Atoi(s)
 	if err != nil {
-		return 0, fmt.Errorf("line %d: invalid %s value %q: %w", lineNum, name, s, err)
+		return 0, fmt.Errorf("invalid %s value %q (expected an integer)", name, s)
 	}
 
 	return v, nil
 }
 
 // parseOptionalInt parses an optional integer field, returning nil if empty.
-func parseOptionalInt(s string) *int {
+func parseOptionalInt(s string) (*int, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
-		return nil
+		return nil, nil
 	}
 
 	v, err := strconv.Atoi(s)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("invalid value %q (expected an integer)", s)
 	}
 
-	return &v
+	return &v, nil
 }
 
 // parseDate parses a date in YYYYMMDD format.
-func parseDate(s string, lineNum int) (time.Time, error) {
+func parseDate(s string) (time.Time, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
-		return time.Time{}, fmt.Errorf("line %d: date is empty", lineNum)
+		return time.Time{}, fmt.Errorf("date is empty")
 	}
 
 	t, err := time.Parse("20060102", s)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("line %d: invalid date format %q (expected YYYYMMDD): %w", lineNum, s, err)
+		return time.Time{}, fmt.Errorf("invalid date format %q (expected YYYYMMDD)", s)
 	}
 
 	return t, nil
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"io"
+	"time"
+)
+
+// HourlyRecord represents a single hourly weather observation.
+// Hour is the KNMI hour number (1-24); an observation with hour H covers
+// the hour ending at H:00 UT on Date.
+type HourlyRecord struct {
+	StationID int
+	Date      time.Time
+	Hour      int
+	DD        *int
+	FH        *int
+	FF        *int
+	FX        *int
+	T         *int
+	T10N      *int
+	TD        *int
+	SQ        *int
+	Q         *int
+	DR        *int
+	RH        *int
+	P         *int
+	VV        *int
+	N         *int
+	U         *int
+	WW        *int
+	IX        *int
+	M         *int
+	R         *int
+	S         *int
+	O         *int
+	Y         *int
+
+	// RHTrace reports that KNMI recorded a trace amount (TraceValue) for
+	// RH; the field itself is then 0.
+	RHTrace bool
+}
+
+// Time returns the end of the hour the observation covers.
+func (r *HourlyRecord) Time() time.Time {
+	return r.Date.Add(time.Duration(r.Hour) * time.Hour)
+}
+
+// DefaultHourlyColumns is the column layout of KNMI hourly data files. It is
+// used for files that do not contain a "# STN,YYYYMMDD,HH,..." header line.
+var DefaultHourlyColumns = []string{
+	"STN", "YYYYMMDD", "HH", "DD", "FH", "FF", "FX", "T", "T10N", "TD", "SQ", "Q",
+	"DR", "RH", "P", "VV", "N", "U", "WW", "IX", "M", "R", "S", "O", "Y",
+}
+
+// hourlySchema describes how KNMI hourly data columns fill an HourlyRecord.
+var hourlySchema = &schema[HourlyRecord]{
+	defaultColumns: DefaultHourlyColumns,
+	keys: map[string]keyField[HourlyRecord]{
+		"STN": {name: "station_id", field: func(r *HourlyRecord) *int { return &r.StationID }},
+		"HH":  {name: "hour", field: func(r *HourlyRecord) *int { return &r.Hour }},
+	},
+	date: func(r *HourlyRecord) *time.Time { return &r.Date },
+	fields: map[string]func(*HourlyRecord) **int{
+		"DD":   func(r *HourlyRecord) **int { return &r.DD },
+		"FH":   func(r *HourlyRecord) **int { return &r.FH },
+		"FF":   func(r *HourlyRecord) **int { return &r.FF },
+		"FX":   func(r *HourlyRecord) **int { return &r.FX },
+		"T":    func(r *HourlyRecord) **int { return &r.T },
+		"T10N": func(r *HourlyRecord) **int { return &r.T10N },
+		"TD":   func(r *HourlyRecord) **int { return &r.TD },
+		"SQ":   func(r *HourlyRecord) **int { return &r.SQ },
+		"Q":    func(r *HourlyRecord) **int { return &r.Q },
+		"DR":   func(r *HourlyRecord) **int { return &r.DR },
+		"RH":   func(r *HourlyRecord) **int { return &r.RH },
+		"P":    func(r *HourlyRecord) **int { return &r.P },
+		"VV":   func(r *HourlyRecord) **int { return &r.VV },
+		"N":    func(r *HourlyRecord) **int { return &r.N },
+		"U":    func(r *HourlyRecord) **int { return &r.U },
+		"WW":   func(r *HourlyRecord) **int { return &r.WW },
+		"IX":   func(r *HourlyRecord) **int { return &r.IX },
+		"M":    func(r *HourlyRecord) **int { return &r.M },
+		"R":    func(r *HourlyRecord) **int { return &r.R },
+		"S":    func(r *HourlyRecord) **int { return &r.S },
+		"O":    func(r *HourlyRecord) **int { return &r.O },
+		"Y":    func(r *HourlyRecord) **int { return &r.Y },
+	},
+	traces: map[string]func(*HourlyRecord) *bool{
+		"RH": func(r *HourlyRecord) *bool { return &r.RHTrace },
+	},
+}
+
+// ParseHourly parses KNMI hourly weather data (uurgeg files) from a reader.
+// Columns are mapped by name using the file's header line; files without
+// a header are parsed using DefaultHourlyColumns.
+func ParseHourly(r io.Reader) (*Result[HourlyRecord], error) {
+	return parseRecords(r, hourlySchema)
+}
+
+// NewHourlyReader returns a Reader that streams KNMI hourly weather data
+// (uurgeg files) from r one record at a time.
+func NewHourlyReader(r io.Reader) *Reader[HourlyRecord] {
+	return newReader(r, hourlySchema)
+}
@@ -0,0 +1,221 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of parsing a KNMI data file.
+type Result[T any] struct {
+	// Records are the parsed observations.
+	Records []T
+
+	// Columns is the column layout the data lines were parsed with.
+	Columns []string
+
+	// UnknownColumns lists header columns that do not map to a record
+	// field. Their values are ignored.
+	UnknownColumns []string
+
+	// Stations lists the stations described in the file header.
+	Stations []Station
+}
+
+// schema describes how the columns of a KNMI data file fill a record type.
+type schema[T any] struct {
+	// defaultColumns is the layout used for files without a header line.
+	defaultColumns []string
+
+	// keys maps required integer columns (e.g. STN) to record fields.
+	keys map[string]keyField[T]
+
+	// date returns the record field filled from the YYYYMMDD column.
+	date func(*T) *time.Time
+
+	// fields maps optional integer columns to record fields.
+	fields map[string]func(*T) **int
+
+	// traces maps precipitation columns that encode a trace amount as
+	// TraceValue to the flag recording it. The column itself is set to 0.
+	traces map[string]func(*T) *bool
+}
+
+// keyField is a required integer column of a record.
+type keyField[T any] struct {
+	name  string
+	field func(*T) *int
+}
+
+// parseRecords parses KNMI data from a reader into records described by s.
+func parseRecords[T any](r io.Reader, s *schema[T]) (*Result[T], error) {
+	reader := newReader(r, s)
+
+	result := &Result[T]{}
+	for reader.Next() {
+		result.Records = append(result.Records, reader.Record())
+	}
+	if err := reader.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Columns = reader.Columns()
+	result.UnknownColumns = reader.UnknownColumns()
+	result.Stations = reader.Stations()
+
+	return result, nil
+}
+
+// headerNameRegex matches a valid KNMI column name.
+var headerNameRegex = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+
+// parseHeader recognises a column header line such as
+// "# STN,YYYYMMDD,DDVEC,FHVEC,   FG" and returns its column names. The
+// leading "#" is optional. A line is a header if every field is a column
+// name and it includes STN and YYYYMMDD. Columns the schema does not know
+// are accepted here; their values are skipped when data lines are parsed,
+// and they are reported via UnknownColumns.
+func parseHeader(line string) ([]string, bool) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+
+	// Column names start with a letter; data lines start with the station
+	// number. Rejecting them here keeps them off the split below, which
+	// would otherwise run for every data line.
+	if line == "" || (line[0] >= '0' && line[0] <= '9') || !strings.Contains(line, ",") {
+		return nil, false
+	}
+
+	columns := strings.Split(line, ",")
+	for i := range columns {
+		columns[i] = strings.ToUpper(strings.TrimSpace(columns[i]))
+	}
+
+	// KNMI files may end lines with a trailing comma
+	if columns[len(columns)-1] == "" {
+		columns = columns[:len(columns)-1]
+	}
+
+	hasStation, hasDate := false, false
+	for _, name := range columns {
+		if !headerNameRegex.MatchString(name) {
+			return nil, false
+		}
+		hasStation = hasStation || name == "STN"
+		hasDate = hasDate || name == "YYYYMMDD"
+	}
+
+	return columns, hasStation && hasDate
+}
+
+// layout maps the columns of a data line to record fields.
+type layout[T any] struct {
+	schema  *schema[T]
+	columns []string
+	dateIdx int
+	keys    []*keyField[T]
+	fields  []func(*T) **int
+	traces  []func(*T) *bool
+	unknown []string
+}
+
+// newLayout creates a layout for the given column names.
+func newLayout[T any](s *schema[T], columns []string) (*layout[T], error) {
+	l := &layout[T]{
+		schema:  s,
+		columns: columns,
+		dateIdx: -1,
+		keys:    make([]*keyField[T], len(columns)),
+		fields:  make([]func(*T) **int, len(columns)),
+		traces:  make([]func(*T) *bool, len(columns)),
+	}
+
+	found := 0
+	for i, name := range columns {
+		if name == "YYYYMMDD" {
+			l.dateIdx = i
+			continue
+		}
+		if key, ok := s.keys[name]; ok {
+			l.keys[i] = &key
+			found++
+			continue
+		}
+		if field, ok := s.fields[name]; ok {
+			l.fields[i] = field
+			l.traces[i] = s.traces[name]
+		} else {
+			l.unknown = append(l.unknown, name)
+		}
+	}
+
+	if l.dateIdx < 0 || found < len(s.keys) {
+		var required []string
+		for name := range s.keys {
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		required = append(required, "YYYYMMDD")
+		return nil, fmt.Errorf("header must contain the %s columns", strings.Join(required, ", "))
+	}
+
+	return l, nil
+}
+
+// parseLine parses a single data line. Lines may end with a trailing comma.
+// Malformed lines are reported as a *ParseError.
+func (l *layout[T]) parseLine(line string, lineNum int) (*T, error) {
+	fail := func(column string, err error) (*T, error) {
+		return nil, &ParseError{Line: lineNum, Column: column, Text: line, Err: err}
+	}
+
+	fields := strings.Split(line, ",")
+	if len(fields) == len(l.columns)+1 && strings.TrimSpace(fields[len(fields)-1]) == "" {
+		fields = fields[:len(l.columns)]
+	}
+
+	if len(fields) != len(l.columns) {
+		return fail("", fmt.Errorf("expected %d columns, got %d", len(l.columns), len(fields)))
+	}
+
+	record := new(T)
+
+	// Parse required fields such as the station ID
+	for i, key := range l.keys {
+		if key == nil {
+			continue
+		}
+		v, err := parseRequiredInt(fields[i], key.name)
+		if err != nil {
+			return fail(l.columns[i], err)
+		}
+		*key.field(record) = v
+	}
+
+	// Parse date (required)
+	date, err := parseDate(fields[l.dateIdx])
+	if err != nil {
+		return fail(l.columns[l.dateIdx], err)
+	}
+	*l.schema.date(record) = date
+
+	// Parse optional integer fields
+	for i, field := range l.fields {
+		if field == nil {
+			continue
+		}
+		v, err := parseOptionalInt(fields[i])
+		if err != nil {
+			return fail(l.columns[i], err)
+		}
+		if trace := l.traces[i]; trace != nil && v != nil && *v == TraceValue {
+			*v = 0
+			*trace(record) = true
+		}
+		*field(record) = v
+	}
+
+	return record, nil
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseMode controls how a Reader handles malformed data lines.
+type ParseMode int
+
+const (
+	// ModeStrict stops reading at the first malformed data line, such as a
+	// line with a missing date or a non-numeric measurement.
+	ModeStrict ParseMode = iota
+
+	// ModeLenient skips malformed data lines and keeps them as rejects,
+	// so the remaining lines of a file can still be read.
+	ModeLenient
+)
+
+// ParseModes lists the names of the parse modes, as accepted by ParseModeFromName.
+var ParseModes = []string{"strict", "lenient"}
+
+// String returns the name of the parse mode.
+func (m ParseMode) String() string {
+	if int(m) >= 0 && int(m) < len(ParseModes) {
+		return ParseModes[m]
+	}
+	return fmt.Sprintf("ParseMode(%d)", int(m))
+}
+
+// ParseModeFromName returns the parse mode with the given name, "strict" or "lenient".
+func ParseModeFromName(name string) (ParseMode, error) {
+	for i, mode := range ParseModes {
+		if strings.EqualFold(name, mode) {
+			return ParseMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid parse mode %q (expected %s)", name, strings.Join(ParseModes, " or "))
+}
+
+// ParseError describes a malformed data line. In strict mode it is returned
+// by Reader.Err; in lenient mode the line is skipped and the error is kept
+// in Reader.Rejects.
+type ParseError struct {
+	// Line is the line number in the data file, starting at 1.
+	Line int
+
+	// Column is the name of the column holding the malformed value, or ""
+	// if the line as a whole is malformed.
+	Column string
+
+	// Text is the content of the line.
+	Text string
+
+	// Err describes what is wrong with the line.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("line %d, column %s: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"io"
+	"time"
+)
+
+// PrecipitationRecord represents a daily observation of a KNMI volunteer
+// precipitation station (neerslaggeg files).
+type PrecipitationRecord struct {
+	StationID int
+	Date      time.Time
+	RD        *int
+	SX        *int
+}
+
+// DefaultPrecipitationColumns is the column layout of KNMI precipitation
+// station files. It is used for files that do not contain a header line.
+var DefaultPrecipitationColumns = []string{"STN", "YYYYMMDD", "RD", "SX"}
+
+// precipitationSchema describes how KNMI precipitation columns fill a PrecipitationRecord.
+var precipitationSchema = &schema[PrecipitationRecord]{
+	defaultColumns: DefaultPrecipitationColumns,
+	keys: map[string]keyField[PrecipitationRecord]{
+		"STN": {name: "station_id", field: func(r *PrecipitationRecord) *int { return &r.StationID }},
+	},
+	date: func(r *PrecipitationRecord) *time.Time { return &r.Date },
+	fields: map[string]func(*PrecipitationRecord) **int{
+		"RD": func(r *PrecipitationRecord) **int { return &r.RD },
+		"SX": func(r *PrecipitationRecord) **int { return &r.SX },
+	},
+}
+
+// ParsePrecipitation parses KNMI precipitation station data (neerslaggeg
+// files) from a reader. Columns are mapped by name using the file's header
+// line; files without a header are parsed using DefaultPrecipitationColumns.
+func ParsePrecipitation(r io.Reader) (*Result[PrecipitationRecord], error) {
+	return parseRecords(r, precipitationSchema)
+}
+
+// NewPrecipitationReader returns a Reader that streams KNMI precipitation
+// station data (neerslaggeg files) from r one record at a time.
+func NewPrecipitationReader(r io.Reader) *Reader[PrecipitationRecord] {
+	return newReader(r, precipitationSchema)
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reader reads the records of a KNMI data file one line at a time, so files
+// of any size can be processed in bounded memory.
+//
+// Records are read by calling Next until it returns false, then checking
+// Err. The station metadata and column layout of the file header are
+// available once the first record has been read.
+//
+// By default the reader stops at the first malformed data line. In lenient
+// mode (see SetMode) such lines are skipped and kept in Rejects instead,
+// until they are taken with TakeRejects.
+type Reader[T any] struct {
+	scanner    *bufio.Scanner
+	schema     *schema[T]
+	layout     *layout[T]
+	mode       ParseMode
+	unknown    []string
+	stations   []Station
+	inStations bool
+	lineNum    int
+	record     T
+	rejects    []*ParseError
+	err        error
+}
+
+// newReader creates a reader for records described by s.
+func newReader[T any](r io.Reader, s *schema[T]) *Reader[T] {
+	layout, err := newLayout(s, s.defaultColumns)
+	return &Reader[T]{
+		scanner: bufio.NewScanner(r),
+		schema:  s,
+		layout:  layout,
+		err:     err,
+	}
+}
+
+// Next advances to the next record. It returns false at the end of the
+// input or when an error occurred.
+func (r *Reader[T]) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	for r.scanner.Scan() {
+		r.lineNum++
+		line := strings.TrimSpace(r.scanner.Text())
+
+		// Collect station metadata from the block following the
+		// "STN LON(east) LAT(north) ALT(m) NAME" line
+		if r.inStations {
+			if station, ok := parseStationLine(line); ok {
+				r.stations = append(r.stations, station)
+				continue
+			}
+			r.inStations = false
+		}
+		if isStationHeader(line) {
+			r.inStations = true
+			continue
+		}
+
+		// Skip empty lines
+		if line == "" {
+			continue
+		}
+
+		// Switch to the column layout declared by the header line
+		if columns, ok := parseHeader(line); ok {
+			layout, err := newLayout(r.schema, columns)
+			if err != nil {
+				r.err = fmt.Errorf("line %d: %w", r.lineNum, err)
+				return false
+			}
+			r.layout = layout
+			r.unknown = layout.unknown
+			continue
+		}
+
+		// Skip comments
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Skip header/description lines - data lines start with station ID (digits)
+		// KNMI files have description text before the actual data
+		if line[0] < '0' || line[0] > '9' {
+			continue
+		}
+
+		record, err := r.layout.parseLine(line, r.lineNum)
+		var parseErr *ParseError
+		if r.mode == ModeLenient && errors.As(err, &parseErr) {
+			r.rejects = append(r.rejects, parseErr)
+			continue
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+
+		r.record = *record
+		return true
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		r.err = fmt.Errorf("reading input: %w", err)
+	}
+	return false
+}
+
+// SetMode sets how malformed data lines are handled. It must be called
+// before the first call to Next.
+func (r *Reader[T]) SetMode(mode ParseMode) {
+	r.mode = mode
+}
+
+// Rejects returns the malformed data lines skipped in lenient mode that
+// have not been taken with TakeRejects.
+func (r *Reader[T]) Rejects() []*ParseError {
+	return r.rejects
+}
+
+// TakeRejects returns the malformed data lines skipped in lenient mode
+// since the last call and forgets them, so a long file does not keep all
+// of its rejects in memory.
+func (r *Reader[T]) TakeRejects() []*ParseError {
+	rejects := r.rejects
+	r.rejects = nil
+	return rejects
+}
+
+// Record returns the record read by the last call to Next.
+func (r *Reader[T]) Record() T {
+	return r.record
+}
+
+// Err returns the first error encountered while reading.
+func (r *Reader[T]) Err() error {
+	return r.err
+}
+
+// Columns returns the column layout data lines are parsed with.
+func (r *Reader[T]) Columns() []string {
+	if r.layout == nil {
+		return nil
+	}
+	return r.layout.columns
+}
+
+// UnknownColumns returns the header columns that do not map to a record
+// field. Their values are ignored.
+func (r *Reader[T]) UnknownColumns() []string {
+	return r.unknown
+}
+
+// Stations returns the stations described in the file header so far.
+func (r *Reader[T]) Stations() []Station {
+	return r.stations
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Station describes a KNMI station as listed in the header of its data files.
+type Station struct {
+	ID        int
+	Longitude float64
+	Latitude  float64
+	Altitude  float64
+	Name      string
+}
+
+// stationHeaderRegex matches the header of the station block, e.g.
+// "# STN         LON(east)   LAT(north)  ALT(m)      NAME".
+var stationHeaderRegex = regexp.MustCompile(`^STN\s+LON\(east\)\s+LAT\(north\)\s+ALT\(m\)\s+NAME`)
+
+// stationLineRegex matches a station line within the station block, e.g.
+// "# 260         5.180       52.100      1.90        De Bilt". The station
+// number may be followed by a colon.
+var stationLineRegex = regexp.MustCompile(`^(\d+):?\s+(-?\d+(?:\.\d+)?)\s+(-?\d+(?:\.\d+)?)\s+(-?\d+(?:\.\d+)?)\s+(.+)$`)
+
+// isStationHeader reports whether a line starts the station block.
+func isStationHeader(line string) bool {
+	return stationHeaderRegex.MatchString(stripComment(line))
+}
+
+// parseStationLine parses a line of the station block.
+func parseStationLine(line string) (Station, bool) {
+	matches := stationLineRegex.FindStringSubmatch(stripComment(line))
+	if matches == nil {
+		return Station{}, false
+	}
+
+	id, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return Station{}, false
+	}
+
+	var coords [3]float64
+	for i := range coords {
+		coords[i], err = strconv.ParseFloat(matches[i+2], 64)
+		if err != nil {
+			return Station{}, false
+		}
+	}
+
+	return Station{
+		ID:        id,
+		Longitude: coords[0],
+		Latitude:  coords[1],
+		Altitude:  coords[2],
+		Name:      strings.TrimSpace(matches[5]),
+	}, true
+}
+
+// stripComment removes the leading "#" of a header line and surrounding whitespace.
+func stripComment(line string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+}
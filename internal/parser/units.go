@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ColumnInfo describes a KNMI data column: what it measures and how its raw
+// integer values convert to a physical unit.
+type ColumnInfo struct {
+	// Name is the KNMI column name, e.g. "TG".
+	Name string
+
+	// Description says what the column measures.
+	Description string
+
+	// Unit is the unit of the converted value, e.g. "°C", or "" for
+	// columns holding an hour, a code or a count.
+	Unit string
+
+	// Divisor converts a raw value to Unit, e.g. 10 for values in tenths.
+	// Dividing rather than multiplying by 0.1 keeps values such as 18.5 exact.
+	Divisor float64
+}
+
+// Convert returns a raw column value in the column's unit, or nil if the
+// value is missing.
+func (c ColumnInfo) Convert(raw *int) *float64 {
+	if raw == nil {
+		return nil
+	}
+	v := float64(*raw) / c.Divisor
+	return &v
+}
+
+// Format formats a raw column value in the column's unit, e.g. "18.5 °C"
+// for TG 185. Values of columns without a unit are formatted as is.
+func (c ColumnInfo) Format(raw int) string {
+	if c.Unit == "" {
+		return fmt.Sprintf("%d", raw)
+	}
+	decimals := 0
+	if c.Divisor > 1 {
+		decimals = 1
+	}
+	return fmt.Sprintf("%.*f %s", decimals, float64(raw)/c.Divisor, c.Unit)
+}
+
+// WeatherColumns describes the measurement columns of KNMI daily data files,
+// in DefaultColumns order.
+var WeatherColumns = []ColumnInfo{
+	{"DDVEC", "Vector mean wind direction (360 = north, 0 = calm or variable)", "°", 1},
+	{"FHVEC", "Vector mean wind speed", "m/s", 10},
+	{"FG", "Daily mean wind speed", "m/s", 10},
+	{"FHX", "Maximum hourly mean wind speed", "m/s", 10},
+	{"FHXH", "Hour in which FHX was measured", "", 1},
+	{"FHN", "Minimum hourly mean wind speed", "m/s", 10},
+	{"FHNH", "Hour in which FHN was measured", "", 1},
+	{"FXX", "Maximum wind gust", "m/s", 10},
+	{"FXXH", "Hour in which FXX was measured", "", 1},
+	{"TG", "Daily mean temperature", "°C", 10},
+	{"TN", "Minimum temperature", "°C", 10},
+	{"TNH", "Hour in which TN was measured", "", 1},
+	{"TX", "Maximum temperature", "°C", 10},
+	{"TXH", "Hour in which TX was measured", "", 1},
+	{"T10N", "Minimum temperature at 10 cm above the ground", "°C", 10},
+	{"T10NH", "6-hour period in which T10N was measured (6, 12, 18 or 24)", "", 1},
+	{"SQ", "Sunshine duration", "h", 10},
+	{"SP", "Percentage of the longest possible sunshine duration", "%", 1},
+	{"Q", "Global radiation", "J/cm²", 1},
+	{"DR", "Precipitation duration", "h", 10},
+	{"RH", "Daily precipitation amount", "mm", 10},
+	{"RHX", "Maximum hourly precipitation amount", "mm", 10},
+	{"RHXH", "Hour in which RHX was measured", "", 1},
+	{"PG", "Daily mean sea level pressure", "hPa", 10},
+	{"PX", "Maximum hourly sea level pressure", "hPa", 10},
+	{"PXH", "Hour in which PX was measured", "", 1},
+	{"PN", "Minimum hourly sea level pressure", "hPa", 10},
+	{"PNH", "Hour in which PN was measured", "", 1},
+	{"VVN", "Minimum visibility (coded)", "", 1},
+	{"VVNH", "Hour in which VVN was measured", "", 1},
+	{"VVX", "Maximum visibility (coded)", "", 1},
+	{"VVXH", "Hour in which VVX was measured", "", 1},
+	{"NG", "Mean cloud cover (9 = sky invisible)", "okta", 1},
+	{"UG", "Daily mean relative humidity", "%", 1},
+	{"UX", "Maximum relative humidity", "%", 1},
+	{"UXH", "Hour in which UX was measured", "", 1},
+	{"UN", "Minimum relative humidity", "%", 1},
+	{"UNH", "Hour in which UN was measured", "", 1},
+	{"EV24", "Potential evapotranspiration (Makkink)", "mm", 10},
+}
+
+// weatherColumnIndex maps KNMI column names to their WeatherColumns entry.
+var weatherColumnIndex = func() map[string]ColumnInfo {
+	index := make(map[string]ColumnInfo, len(WeatherColumns))
+	for _, c := range WeatherColumns {
+		index[c.Name] = c
+	}
+	return index
+}()
+
+// LookupWeatherColumn returns the description of a daily data column. The
+// name is matched case-insensitively, so database column names such as
+// "tg" can be looked up as well.
+func LookupWeatherColumn(name string) (ColumnInfo, bool) {
+	c, ok := weatherColumnIndex[strings.ToUpper(name)]
+	return c, ok
+}
+
+// Observation is a daily weather observation with its measurements converted
+// from KNMI's raw integers (often in tenths) to physical units. Columns
+// holding an hour or a code are left out; they are available on
+// WeatherRecord. Missing measurements are nil.
+type Observation struct {
+	StationID int
+	Date      time.Time
+
+	// Wind in degrees (direction) and m/s (speeds)
+	WindDirection      *float64
+	VectorWindSpeed    *float64
+	MeanWindSpeed      *float64
+	MaxHourlyWindSpeed *float64
+	MinHourlyWindSpeed *float64
+	MaxWindGust        *float64
+
+	// Temperature in °C
+	MeanTemperature      *float64
+	MinTemperature       *float64
+	MaxTemperature       *float64
+	MinGroundTemperature *float64
+
+	// Sunshine in hours and percent, global radiation in J/cm²
+	SunshineDuration   *float64
+	SunshinePercentage *float64
+	GlobalRadiation    *float64
+
+	// Precipitation duration in hours, amounts in mm. Trace amounts are 0
+	// with the matching trace flag set.
+	PrecipitationDuration       *float64
+	Precipitation               *float64
+	MaxHourlyPrecipitation      *float64
+	PrecipitationDurationTrace  bool
+	PrecipitationTrace          bool
+	MaxHourlyPrecipitationTrace bool
+
+	// Sea level pressure in hPa
+	MeanPressure *float64
+	MaxPressure  *float64
+	MinPressure  *float64
+
+	// Cloud cover in okta, relative humidity in percent
+	CloudCover   *float64
+	MeanHumidity *float64
+	MaxHumidity  *float64
+	MinHumidity  *float64
+
+	// Potential evapotranspiration in mm
+	Evapotranspiration *float64
+}
+
+// Observation converts the record's measurements to physical units as
+// described by WeatherColumns.
+func (r *WeatherRecord) Observation() Observation {
+	convert := func(column string, raw *int) *float64 {
+		return weatherColumnIndex[column].Convert(raw)
+	}
+
+	return Observation{
+		StationID: r.StationID,
+		Date:      r.Date,
+
+		WindDirection:      convert("DDVEC", r.DDVEC),
+		VectorWindSpeed:    convert("FHVEC", r.FHVEC),
+		MeanWindSpeed:      convert("FG", r.FG),
+		MaxHourlyWindSpeed: convert("FHX", r.FHX),
+		MinHourlyWindSpeed: convert("FHN", r.FHN),
+		MaxWindGust:        convert("FXX", r.FXX),
+
+		MeanTemperature:      convert("TG", r.TG),
+		MinTemperature:       convert("TN", r.TN),
+		MaxTemperature:       convert("TX", r.TX),
+		MinGroundTemperature: convert("T10N", r.T10N),
+
+		SunshineDuration:   convert("SQ", r.SQ),
+		SunshinePercentage: convert("SP", r.SP),
+		GlobalRadiation:    convert("Q", r.Q),
+
+		PrecipitationDuration:       convert("DR", r.DR),
+		Precipitation:               convert("RH", r.RH),
+		MaxHourlyPrecipitation:      convert("RHX", r.RHX),
+		PrecipitationDurationTrace:  r.DRTrace,
+		PrecipitationTrace:          r.RHTrace,
+		MaxHourlyPrecipitationTrace: r.RHXTrace,
+
+		MeanPressure: convert("PG", r.PG),
+		MaxPressure:  convert("PX", r.PX),
+		MinPressure:  convert("PN", r.PN),
+
+		CloudCover:   convert("NG", r.NG),
+		MeanHumidity: convert("UG", r.UG),
+		MaxHumidity:  convert("UX", r.UX),
+		MinHumidity:  convert("UN", r.UN),
+
+		Evapotranspiration: convert("EV24", r.EV24),
+	}
+}
@@ -0,0 +1,148 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// Rule is a plausibility check on a weather record.
+type Rule struct {
+	// Name identifies the rule, e.g. in --rule-severity.
+	Name string
+
+	// Description says what the rule checks.
+	Description string
+
+	// check returns a message describing the implausible values, or ""
+	// if the record passes. Missing values always pass.
+	check func(rec *parser.WeatherRecord) string
+}
+
+// rules are the built-in rules, applied in this order.
+var rules = []Rule{
+	rangeRule("wind-direction", "DDVEC is between 0 and 360 degrees", 0, 360, "DDVEC"),
+	minRule("non-negative", "wind speeds, sunshine, radiation, precipitation and evapotranspiration are not negative", 0,
+		"FHVEC", "FG", "FHX", "FHN", "FXX", "SQ", "Q", "DR", "RH", "RHX", "EV24"),
+	rangeRule("hour-range", "hour columns (FHXH, TNH, ...) are between 1 and 24", 1, 24,
+		"FHXH", "FHNH", "FXXH", "TNH", "TXH", "RHXH", "PXH", "PNH", "VVNH", "VVXH", "UXH", "UNH"),
+	oneOfRule("t10n-period", "T10NH is one of the 6-hour periods 6, 12, 18 and 24", []int{6, 12, 18, 24}, "T10NH"),
+	rangeRule("temperature-range", "TG, TN, TX and T10N are between -50 and 50 °C", -500, 500, "TG", "TN", "TX", "T10N"),
+	rangeRule("sunshine-percentage", "SP is between 0 and 100%", 0, 100, "SP"),
+	rangeRule("pressure-range", "PG, PX and PN are between 900 and 1100 hPa", 9000, 11000, "PG", "PX", "PN"),
+	rangeRule("visibility-code", "VVN and VVX are visibility codes between 0 and 89", 0, 89, "VVN", "VVX"),
+	rangeRule("cloud-cover", "NG is between 0 and 9 okta", 0, 9, "NG"),
+	rangeRule("humidity-range", "UG, UX and UN are between 0 and 100%", 0, 100, "UG", "UX", "UN"),
+	orderRule("temperature-order", "TN <= TG <= TX", "TN", "TG", "TX"),
+	orderRule("wind-speed-order", "FHN <= FG <= FHX <= FXX", "FHN", "FG", "FHX", "FXX"),
+	orderRule("pressure-order", "PN <= PG <= PX", "PN", "PG", "PX"),
+	orderRule("humidity-order", "UN <= UG <= UX", "UN", "UG", "UX"),
+	orderRule("precipitation-order", "RHX <= RH", "RHX", "RH"),
+}
+
+// Rules returns the built-in rules.
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// formatRaw formats a raw value of a column in the column's unit.
+func formatRaw(column string, raw int) string {
+	if info, ok := parser.LookupWeatherColumn(column); ok {
+		return info.Format(raw)
+	}
+	return fmt.Sprint(raw)
+}
+
+// formatValue formats a raw value of a column together with the column
+// name, e.g. "TG 18.5 °C".
+func formatValue(column string, raw int) string {
+	return column + " " + formatRaw(column, raw)
+}
+
+// rangeRule returns a rule checking that columns hold raw values between
+// min and max inclusive.
+func rangeRule(name, description string, min, max int, columns ...string) Rule {
+	return Rule{
+		Name:        name,
+		Description: description,
+		check: func(rec *parser.WeatherRecord) string {
+			for _, column := range columns {
+				if v := rec.Value(column); v != nil && (*v < min || *v > max) {
+					return fmt.Sprintf("%s outside %s to %s", formatValue(column, *v), formatRaw(column, min), formatRaw(column, max))
+				}
+			}
+			return ""
+		},
+	}
+}
+
+// minRule returns a rule checking that columns hold raw values of at least min.
+func minRule(name, description string, min int, columns ...string) Rule {
+	return Rule{
+		Name:        name,
+		Description: description,
+		check: func(rec *parser.WeatherRecord) string {
+			for _, column := range columns {
+				if v := rec.Value(column); v != nil && *v < min {
+					return fmt.Sprintf("%s below %s", formatValue(column, *v), formatRaw(column, min))
+				}
+			}
+			return ""
+		},
+	}
+}
+
+// oneOfRule returns a rule checking that columns hold one of the allowed raw values.
+func oneOfRule(name, description string, allowed []int, columns ...string) Rule {
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		names[i] = fmt.Sprint(a)
+	}
+
+	return Rule{
+		Name:        name,
+		Description: description,
+		check: func(rec *parser.WeatherRecord) string {
+			for _, column := range columns {
+				v := rec.Value(column)
+				if v == nil {
+					continue
+				}
+				found := false
+				for _, a := range allowed {
+					found = found || *v == a
+				}
+				if !found {
+					return fmt.Sprintf("%s not one of %s", formatValue(column, *v), strings.Join(names, ", "))
+				}
+			}
+			return ""
+		},
+	}
+}
+
+// orderRule returns a rule checking that the values of columns, given from
+// lowest to highest, do not decrease. Missing values are skipped, so
+// e.g. TN <= TX is still checked when TG is missing.
+func orderRule(name, description string, columns ...string) Rule {
+	return Rule{
+		Name:        name,
+		Description: description,
+		check: func(rec *parser.WeatherRecord) string {
+			prevColumn := ""
+			var prev *int
+			for _, column := range columns {
+				v := rec.Value(column)
+				if v == nil {
+					continue
+				}
+				if prev != nil && *prev > *v {
+					return fmt.Sprintf("%s > %s", formatValue(prevColumn, *prev), formatValue(column, *v))
+				}
+				prevColumn, prev = column, v
+			}
+			return ""
+		},
+	}
+}
@@ -0,0 +1,178 @@
+// Package validate checks parsed KNMI weather records for physically
+// implausible values before they are stored.
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+// Severity controls what happens to a record that breaks a rule.
+type Severity int
+
+const (
+	// SeverityOff disables a rule.
+	SeverityOff Severity = iota
+
+	// SeverityWarn reports the violation and stores the record as usual.
+	SeverityWarn
+
+	// SeverityQuarantine holds the record back for review instead of
+	// storing it with the trusted records.
+	SeverityQuarantine
+
+	// SeverityReject drops the record.
+	SeverityReject
+)
+
+// Severities lists the names of the severities, as accepted by SeverityFromName.
+var Severities = []string{"off", "warn", "quarantine", "reject"}
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	if int(s) >= 0 && int(s) < len(Severities) {
+		return Severities[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// SeverityFromName returns the severity with the given name, e.g. "warn".
+func SeverityFromName(name string) (Severity, error) {
+	for i, severity := range Severities {
+		if strings.EqualFold(name, severity) {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q (expected one of %s)", name, strings.Join(Severities, ", "))
+}
+
+// Violation is a rule broken by a record.
+type Violation struct {
+	// Rule is the name of the rule.
+	Rule string
+
+	// Severity is the severity the rule was applied with.
+	Severity Severity
+
+	// Message describes the implausible values, e.g. "TN 12.0 °C > TX 10.0 °C".
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// Flagged is a record that broke one or more rules.
+type Flagged struct {
+	Record     parser.WeatherRecord
+	Violations []Violation
+}
+
+// Severity returns the highest severity of the record's violations, which
+// decides what happens to the record.
+func (f *Flagged) Severity() Severity {
+	worst := SeverityOff
+	for _, v := range f.Violations {
+		if v.Severity > worst {
+			worst = v.Severity
+		}
+	}
+	return worst
+}
+
+// Result is the outcome of validating a batch of records.
+type Result struct {
+	// Accepted are the records to store: those without violations and
+	// those with warnings only.
+	Accepted []parser.WeatherRecord
+
+	// Warned, Quarantined and Rejected are the flagged records, grouped
+	// by the highest severity of their violations.
+	Warned      []Flagged
+	Quarantined []Flagged
+	Rejected    []Flagged
+}
+
+// Validator checks records against the built-in rules.
+type Validator struct {
+	severities map[string]Severity
+}
+
+// New returns a Validator that applies every rule with the given severity.
+// overrides sets the severity of individual rules by name.
+func New(severity Severity, overrides map[string]Severity) (*Validator, error) {
+	v := &Validator{severities: make(map[string]Severity, len(rules))}
+	for _, rule := range rules {
+		v.severities[rule.Name] = severity
+	}
+	for name, s := range overrides {
+		if _, ok := v.severities[name]; !ok {
+			return nil, fmt.Errorf("unknown validation rule %q (expected one of %s)", name, strings.Join(RuleNames(), ", "))
+		}
+		v.severities[name] = s
+	}
+	return v, nil
+}
+
+// Uses reports whether any rule is applied with the given severity.
+func (v *Validator) Uses(severity Severity) bool {
+	for _, s := range v.severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns the rules a record breaks. Rules that are off are skipped.
+func (v *Validator) Check(rec *parser.WeatherRecord) []Violation {
+	var violations []Violation
+	for _, rule := range rules {
+		severity := v.severities[rule.Name]
+		if severity == SeverityOff {
+			continue
+		}
+		if msg := rule.check(rec); msg != "" {
+			violations = append(violations, Violation{Rule: rule.Name, Severity: severity, Message: msg})
+		}
+	}
+	return violations
+}
+
+// Validate checks a batch of records and sorts them by what should happen
+// to them.
+func (v *Validator) Validate(records []parser.WeatherRecord) *Result {
+	result := &Result{}
+	for _, rec := range records {
+		violations := v.Check(&rec)
+		if len(violations) == 0 {
+			result.Accepted = append(result.Accepted, rec)
+			continue
+		}
+
+		flagged := Flagged{Record: rec, Violations: violations}
+		switch flagged.Severity() {
+		case SeverityReject:
+			result.Rejected = append(result.Rejected, flagged)
+		case SeverityQuarantine:
+			result.Quarantined = append(result.Quarantined, flagged)
+		default:
+			result.Warned = append(result.Warned, flagged)
+			result.Accepted = append(result.Accepted, rec)
+		}
+	}
+	return result
+}
+
+// RuleNames returns the names of the built-in rules in sorted order.
+func RuleNames() []string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.Name
+	}
+	sort.Strings(names)
+	return names
+}
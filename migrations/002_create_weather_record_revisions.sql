@@ -0,0 +1,17 @@
+-- Migration: 002_create_weather_record_revisions.sql
+-- Keeps the previous values of weather records revised by KNMI.
+
+-- Table: weather_record_revisions
+-- Stores a snapshot of a weather record taken just before an upsert changed it.
+CREATE TABLE IF NOT EXISTS weather_record_revisions (
+    id SERIAL PRIMARY KEY,
+    station_id INTEGER NOT NULL,
+    date DATE NOT NULL,
+    previous JSONB NOT NULL,            -- Measurement values before the change, keyed by column
+    changed_columns TEXT[] NOT NULL,    -- Names of the measurement columns that changed
+    synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Index for looking up the history of a single record
+CREATE INDEX IF NOT EXISTS idx_weather_record_revisions_station_date
+    ON weather_record_revisions (station_id, date);
@@ -0,0 +1,51 @@
+-- Migration: 003_create_hourly_records.sql
+-- Adds storage for hourly KNMI observations (uurgeg files).
+
+-- Table: hourly_records
+-- Stores hourly weather observations from KNMI stations.
+CREATE TABLE IF NOT EXISTS hourly_records (
+    id SERIAL PRIMARY KEY,
+    station_id INTEGER NOT NULL,
+    date DATE NOT NULL,
+    hour INTEGER NOT NULL,  -- Hour (1-24), observation covers the hour ending at HH:00 UT
+
+    -- Wind measurements
+    dd INTEGER,             -- Mean wind direction over the last 10 minutes (degrees)
+    fh INTEGER,             -- Hourly mean windspeed (0.1 m/s)
+    ff INTEGER,             -- Mean windspeed over the last 10 minutes (0.1 m/s)
+    fx INTEGER,             -- Max wind gust (0.1 m/s)
+
+    -- Temperature measurements
+    t INTEGER,              -- Temperature at 1.50 m (0.1 °C)
+    t10n INTEGER,           -- Min temp at 10cm in the last 6 hours (0.1 °C)
+    td INTEGER,             -- Dew point temperature (0.1 °C)
+
+    -- Sunshine and radiation
+    sq INTEGER,             -- Sunshine duration (0.1 hour)
+    q INTEGER,              -- Global radiation (J/cm²)
+
+    -- Precipitation
+    dr INTEGER,             -- Precipitation duration (0.1 hour)
+    rh INTEGER,             -- Hourly precipitation (0.1 mm)
+
+    -- Pressure, visibility, clouds and humidity
+    p INTEGER,              -- Air pressure at sea level (0.1 hPa)
+    vv INTEGER,             -- Horizontal visibility (coded)
+    n INTEGER,              -- Cloud cover (octants)
+    u INTEGER,              -- Relative humidity (%)
+
+    -- Weather codes and indicators
+    ww INTEGER,             -- Present weather code
+    ix INTEGER,             -- Indicator present weather code
+    m INTEGER,              -- Fog (0/1)
+    r INTEGER,              -- Rainfall (0/1)
+    s INTEGER,              -- Snow (0/1)
+    o INTEGER,              -- Thunder (0/1)
+    y INTEGER,              -- Ice formation (0/1)
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    -- Constraints
+    CONSTRAINT hourly_records_station_date_hour_unique UNIQUE (station_id, date, hour)
+);
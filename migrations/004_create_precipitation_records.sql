@@ -0,0 +1,19 @@
+-- Migration: 004_create_precipitation_records.sql
+-- Adds storage for KNMI volunteer precipitation stations (neerslaggeg files).
+
+-- Table: precipitation_records
+-- Stores daily observations from KNMI precipitation stations.
+CREATE TABLE IF NOT EXISTS precipitation_records (
+    id SERIAL PRIMARY KEY,
+    station_id INTEGER NOT NULL,
+    date DATE NOT NULL,
+
+    rd INTEGER,             -- Daily precipitation 08:00 UT previous day to 08:00 UT (0.1 mm)
+    sx INTEGER,             -- Snow cover code at 08:00 UT
+
+    -- Metadata
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    -- Constraints
+    CONSTRAINT precipitation_records_station_date_unique UNIQUE (station_id, date)
+);
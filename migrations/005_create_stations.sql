@@ -0,0 +1,22 @@
+-- Migration: 005_create_stations.sql
+-- Adds station metadata taken from the header of KNMI data files.
+
+-- Table: stations
+-- Stores KNMI weather stations. Updated on every sync.
+CREATE TABLE IF NOT EXISTS stations (
+    id INTEGER PRIMARY KEY,         -- KNMI station number (STN)
+    name VARCHAR(255),              -- Station name
+    longitude DOUBLE PRECISION,     -- Longitude (degrees east)
+    latitude DOUBLE PRECISION,      -- Latitude (degrees north)
+    altitude DOUBLE PRECISION,      -- Altitude (m)
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Register stations that already have weather records so the foreign key can be added
+INSERT INTO stations (id)
+SELECT DISTINCT station_id FROM weather_records
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE weather_records
+    ADD CONSTRAINT weather_records_station_fk
+    FOREIGN KEY (station_id) REFERENCES stations (id);
@@ -0,0 +1,11 @@
+-- Migration: 006_create_sources.sql
+-- Remembers the version of each downloaded file so unchanged files are skipped.
+
+-- Table: sources
+-- Stores the HTTP validators of the last successfully synced download per URL.
+CREATE TABLE IF NOT EXISTS sources (
+    url TEXT PRIMARY KEY,           -- Download URL
+    etag TEXT,                      -- ETag response header
+    last_modified TEXT,             -- Last-Modified response header
+    synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
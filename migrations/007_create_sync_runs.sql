@@ -0,0 +1,20 @@
+-- Migration: 007_create_sync_runs.sql
+-- Records which source file each sync processed, for data lineage.
+
+-- Table: sync_runs
+-- One row per data file synced, with the SHA-256 hash of the archive.
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id SERIAL PRIMARY KEY,
+    dataset VARCHAR(50) NOT NULL,   -- Table synced: weather_records, hourly_records or precipitation_records
+    station_id INTEGER,             -- Station the file was synced for (NULL: the stations it holds)
+    source_url TEXT NOT NULL,       -- URL or path the file was read from
+    source_sha256 CHAR(64),         -- SHA-256 of the archive (or text file)
+    source_size BIGINT,             -- Size of the archive in bytes
+    records_read INTEGER NOT NULL,  -- Records parsed from the file
+    inserted INTEGER NOT NULL,      -- Records inserted
+    updated INTEGER NOT NULL,       -- Records updated
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_station ON sync_runs (station_id, finished_at);
@@ -0,0 +1,24 @@
+-- Migration: 008_create_parse_rejects.sql
+-- Keeps the data lines skipped by a sync in lenient parse mode for review.
+
+-- Table: parse_rejects
+-- One row per malformed data line, with the reason it was rejected.
+CREATE TABLE IF NOT EXISTS parse_rejects (
+    id SERIAL PRIMARY KEY,
+    dataset VARCHAR(50) NOT NULL,   -- e.g. weather_records, hourly_records
+    source_url TEXT NOT NULL,       -- URL or path the file was read from
+    file_name TEXT,                 -- Data file within the archive
+    line_number INTEGER NOT NULL,   -- Line number in the data file
+    column_name VARCHAR(50),        -- Column holding the malformed value, if any
+    reason TEXT NOT NULL,           -- Why the line was rejected
+    line TEXT NOT NULL,             -- Content of the line
+    rejected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_parse_rejects_source ON parse_rejects (source_url, rejected_at);
+
+-- KNMI data files are cumulative, so every lenient sync of a file meets its
+-- old malformed lines again. Each rejected line is kept once; a text file
+-- has no file name, so NULL is indexed as an empty name.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_parse_rejects_line
+    ON parse_rejects (dataset, source_url, (COALESCE(file_name, '')), line_number, line);
@@ -0,0 +1,20 @@
+-- Migration: 009_add_trace_precipitation.sql
+-- Stores trace precipitation explicitly instead of KNMI's -1 sentinel.
+
+-- KNMI encodes a trace amount (< 0.05 mm) as -1 in DR, RH and RHX. The
+-- measurement is stored as 0 and the trace is flagged separately, so that
+-- SUM and AVG treat trace as 0 while trace days can still be counted, e.g.
+-- COUNT(*) FILTER (WHERE rh_trace).
+ALTER TABLE weather_records
+    ADD COLUMN IF NOT EXISTS dr_trace BOOLEAN NOT NULL DEFAULT FALSE,   -- DR was a trace amount
+    ADD COLUMN IF NOT EXISTS rh_trace BOOLEAN NOT NULL DEFAULT FALSE,   -- RH was a trace amount
+    ADD COLUMN IF NOT EXISTS rhx_trace BOOLEAN NOT NULL DEFAULT FALSE;  -- RHX was a trace amount
+
+ALTER TABLE hourly_records
+    ADD COLUMN IF NOT EXISTS rh_trace BOOLEAN NOT NULL DEFAULT FALSE;   -- RH was a trace amount
+
+-- Convert the sentinels synced before this migration
+UPDATE weather_records SET dr = 0, dr_trace = TRUE WHERE dr = -1;
+UPDATE weather_records SET rh = 0, rh_trace = TRUE WHERE rh = -1;
+UPDATE weather_records SET rhx = 0, rhx_trace = TRUE WHERE rhx = -1;
+UPDATE hourly_records SET rh = 0, rh_trace = TRUE WHERE rh = -1;
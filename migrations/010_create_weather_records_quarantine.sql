@@ -0,0 +1,62 @@
+-- Migration: 010_create_weather_records_quarantine.sql
+-- Holds daily records that failed a plausibility check with severity
+-- quarantine, so they can be reviewed instead of being dropped or trusted.
+
+-- Table: weather_records_quarantine
+-- Same measurement columns as weather_records, plus the rules that fired.
+-- 'knmi quarantine release' moves a row into weather_records.
+CREATE TABLE IF NOT EXISTS weather_records_quarantine (
+    id SERIAL PRIMARY KEY,
+    station_id INTEGER NOT NULL REFERENCES stations (id),
+    date DATE NOT NULL,
+
+    ddvec INTEGER,
+    fhvec INTEGER,
+    fg INTEGER,
+    fhx INTEGER,
+    fhxh INTEGER,
+    fhn INTEGER,
+    fhnh INTEGER,
+    fxx INTEGER,
+    fxxh INTEGER,
+    tg INTEGER,
+    tn INTEGER,
+    tnh INTEGER,
+    tx INTEGER,
+    txh INTEGER,
+    t10n INTEGER,
+    t10nh INTEGER,
+    sq INTEGER,
+    sp INTEGER,
+    q INTEGER,
+    dr INTEGER,
+    rh INTEGER,
+    rhx INTEGER,
+    rhxh INTEGER,
+    pg INTEGER,
+    px INTEGER,
+    pxh INTEGER,
+    pn INTEGER,
+    pnh INTEGER,
+    vvn INTEGER,
+    vvnh INTEGER,
+    vvx INTEGER,
+    vvxh INTEGER,
+    ng INTEGER,
+    ug INTEGER,
+    ux INTEGER,
+    uxh INTEGER,
+    un INTEGER,
+    unh INTEGER,
+    ev24 INTEGER,
+    dr_trace BOOLEAN NOT NULL DEFAULT FALSE,
+    rh_trace BOOLEAN NOT NULL DEFAULT FALSE,
+    rhx_trace BOOLEAN NOT NULL DEFAULT FALSE,
+
+    rules TEXT[] NOT NULL,              -- Names of the validation rules that fired
+    messages TEXT[] NOT NULL,           -- What each rule found, in rules order
+    quarantined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    -- A record synced again replaces its quarantined copy
+    CONSTRAINT weather_records_quarantine_station_date_unique UNIQUE (station_id, date)
+);
@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"context"
 	"database/sql"
 	"os"
 	"path/filepath"
@@ -45,7 +46,7 @@ CREATE TABLE IF NOT EXISTS test_table (
 // cleanupDatabase drops test tables created during tests.
 func cleanupDatabase(t *testing.T, database *sql.DB) {
 	t.Helper()
-	tables := []string{"test_table", "weather_records", "migrations"}
+	tables := []string{"test_table", "precipitation_records", "hourly_records", "weather_records_quarantine", "weather_record_revisions", "weather_records", "stations", "sources", "sync_runs", "parse_rejects", "migrations"}
 	for _, table := range tables {
 		_, err := database.Exec("DROP TABLE IF EXISTS " + table + " CASCADE")
 		if err != nil {
@@ -57,7 +58,7 @@ func cleanupDatabase(t *testing.T, database *sql.DB) {
 func TestMigrateCommand(t *testing.T) {
 	databaseURL := getTestDatabaseURL(t)
 
-	database, err := db.Connect(databaseURL)
+	database, err := db.Connect(context.Background(), databaseURL)
 	if err != nil {
 		t.Fatalf("failed to connect to database: %v", err)
 	}
@@ -0,0 +1,316 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/cli"
+	"github.com/harrybawsac/knmi-go/internal/db"
+	_ "github.com/lib/pq"
+)
+
+// implausibleInput holds one plausible record and two that fail validation:
+// TN > TX on 2024-01-02 and a relative humidity of 105% on 2024-01-03.
+const implausibleInput = `# STN,YYYYMMDD,   TG,   TN,   TX,   UG
+  260,20240101,   85,   60,  102,   80
+  260,20240102,   85,  120,  102,   80
+  260,20240103,   85,   60,  102,  105
+`
+
+func TestSyncValidation(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+
+	textPath := filepath.Join(t.TempDir(), "etmgeg_260.txt")
+	if err := os.WriteFile(textPath, []byte(implausibleInput), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	tests := []struct {
+		name                string
+		args                []string
+		expectedCount       int
+		expectedQuarantined int
+	}{
+		{name: "warn stores every record", expectedCount: 3},
+		{name: "off stores every record", args: []string{"--validation", "off"}, expectedCount: 3},
+		{name: "reject drops implausible records", args: []string{"--validation", "reject"}, expectedCount: 1},
+		{name: "quarantine holds implausible records back", args: []string{"--validation", "quarantine"}, expectedCount: 1, expectedQuarantined: 2},
+		{
+			name:                "severity per rule",
+			args:                []string{"--validation", "quarantine", "--rule-severity", "humidity-range=warn"},
+			expectedCount:       2,
+			expectedQuarantined: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupDatabase(t, database)
+			applyMigrations(t, database, "")
+
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs(append([]string{"sync", "--input", textPath}, tt.args...))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			var count, quarantined int
+			if err := database.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count); err != nil {
+				t.Fatalf("failed to count records: %v", err)
+			}
+			if err := database.QueryRow("SELECT COUNT(*) FROM weather_records_quarantine").Scan(&quarantined); err != nil {
+				t.Fatalf("failed to count quarantined records: %v", err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("expected %d records, got %d", tt.expectedCount, count)
+			}
+			if quarantined != tt.expectedQuarantined {
+				t.Errorf("expected %d quarantined records, got %d", tt.expectedQuarantined, quarantined)
+			}
+		})
+	}
+}
+
+func TestSyncClearsCorrectedQuarantine(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	// KNMI corrects TN on 2024-01-02 after the first sync
+	textPath := filepath.Join(t.TempDir(), "etmgeg_260.txt")
+	corrected := strings.Replace(implausibleInput, "20240102,   85,  120", "20240102,   85,   60", 1)
+	for _, content := range []string{implausibleInput, corrected} {
+		if err := os.WriteFile(textPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write data file: %v", err)
+		}
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs([]string{"sync", "--input", textPath, "--validation", "quarantine", "--upsert", "--force"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	}
+
+	records, err := db.NewQuarantineRepository(database).List(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("failed to list quarantined records: %v", err)
+	}
+	if len(records) != 1 || records[0].Record.Date.Format("2006-01-02") != "2024-01-03" {
+		t.Errorf("expected only 2024-01-03 to stay in quarantine, got %+v", records)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 records, got %d", count)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	textPath := filepath.Join(t.TempDir(), "etmgeg_260.txt")
+	if err := os.WriteFile(textPath, []byte(implausibleInput), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	run := func(args ...string) error {
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	ctx := context.Background()
+	if err := run("sync", "--input", textPath, "--validation", "quarantine"); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	repo := db.NewQuarantineRepository(database)
+	records, err := repo.List(ctx, 0)
+	if err != nil {
+		t.Fatalf("failed to list quarantined records: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 quarantined records, got %d", len(records))
+	}
+	released, dropped := records[0], records[1]
+	if len(released.Rules) != 1 || released.Rules[0] != "temperature-order" {
+		t.Errorf("expected rule temperature-order, got %v", released.Rules)
+	}
+	if len(released.Messages) != 1 || released.Messages[0] != "TN 12.0 °C > TG 8.5 °C" {
+		t.Errorf("expected the rule's message to be stored, got %v", released.Messages)
+	}
+	if err := run("quarantine", "list", "--station", "260"); err != nil {
+		t.Errorf("quarantine list failed: %v", err)
+	}
+
+	t.Run("release moves the record into weather_records", func(t *testing.T) {
+		if err := run("quarantine", "release", strconv.Itoa(released.ID)); err != nil {
+			t.Fatalf("quarantine release failed: %v", err)
+		}
+
+		stored, err := db.NewWeatherRepository(database).GetRecord(ctx, 260, released.Record.Date)
+		if err != nil {
+			t.Fatalf("failed to get record: %v", err)
+		}
+		if stored == nil || stored.TN == nil || *stored.TN != 120 {
+			t.Errorf("expected released record with tn=120, got %+v", stored)
+		}
+
+		_, err = repo.Release(ctx, released.ID)
+		if !errors.Is(err, db.ErrNotQuarantined) {
+			t.Errorf("expected ErrNotQuarantined for a released record, got %v", err)
+		}
+	})
+
+	t.Run("drop discards the record", func(t *testing.T) {
+		if err := run("quarantine", "drop", strconv.Itoa(dropped.ID)); err != nil {
+			t.Fatalf("quarantine drop failed: %v", err)
+		}
+		if remaining, _ := repo.List(ctx, 0); len(remaining) != 0 {
+			t.Errorf("expected no quarantined records, got %d", len(remaining))
+		}
+		if err := repo.Drop(ctx, dropped.ID); !errors.Is(err, db.ErrNotQuarantined) {
+			t.Errorf("expected ErrNotQuarantined, got %v", err)
+		}
+	})
+}
+
+func TestQuarantineReleaseRevision(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	run := func(args ...string) error {
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	// KNMI revises TN on 2024-01-02 to an implausible value after the
+	// first sync; the revision is quarantined and the stored record kept
+	textPath := filepath.Join(t.TempDir(), "etmgeg_260.txt")
+	plausible := strings.Replace(implausibleInput, "20240102,   85,  120", "20240102,   85,   60", 1)
+	for _, content := range []string{plausible, implausibleInput} {
+		if err := os.WriteFile(textPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write data file: %v", err)
+		}
+		if err := run("sync", "--input", textPath, "--validation", "quarantine", "--upsert", "--force"); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	repo := db.NewQuarantineRepository(database)
+	weather := db.NewWeatherRepository(database)
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	stored, err := weather.GetRecord(ctx, 260, date)
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+	if stored == nil || stored.TN == nil || *stored.TN != 60 {
+		t.Fatalf("expected the stored record to keep tn=60, got %+v", stored)
+	}
+
+	records, err := repo.List(ctx, 260)
+	if err != nil {
+		t.Fatalf("failed to list quarantined records: %v", err)
+	}
+	var revision *db.QuarantinedRecord
+	for i := range records {
+		if records[i].Record.Date.Equal(date) {
+			revision = &records[i]
+		}
+	}
+	if revision == nil {
+		t.Fatalf("expected the revision of 2024-01-02 to be quarantined, got %+v", records)
+	}
+
+	if err := run("quarantine", "release", strconv.Itoa(revision.ID)); err != nil {
+		t.Fatalf("quarantine release failed: %v", err)
+	}
+
+	stored, err = weather.GetRecord(ctx, 260, date)
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+	if stored == nil || stored.TN == nil || *stored.TN != 120 {
+		t.Errorf("expected the released revision with tn=120, got %+v", stored)
+	}
+
+	revisions, err := weather.GetRevisions(ctx, 260, date)
+	if err != nil {
+		t.Fatalf("failed to get revisions: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].Previous["tn"] == nil || *revisions[0].Previous["tn"] != 60 {
+		t.Errorf("expected one revision with previous tn=60, got %+v", revisions)
+	}
+
+	// Syncing the same file again leaves the released record alone
+	if err := run("sync", "--input", textPath, "--validation", "quarantine", "--upsert", "--force"); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	records, err = repo.List(ctx, 260)
+	if err != nil {
+		t.Fatalf("failed to list quarantined records: %v", err)
+	}
+	for _, q := range records {
+		if q.Record.Date.Equal(date) {
+			t.Errorf("expected the released record not to be quarantined again")
+		}
+	}
+}
@@ -3,14 +3,22 @@ package integration
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/harrybawsac/knmi-go/internal/cli"
 	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/migration"
 	_ "github.com/lib/pq"
 )
 
@@ -20,108 +28,79 @@ func createMockKNMIServer(t *testing.T) *httptest.Server {
 
 	csvData := `# KNMI - Royal Netherlands Meteorological Institute
 # Station 260 - De Bilt
+#
+# STN         LON(east)   LAT(north)  ALT(m)      NAME
+# 260         5.180       52.100      1.90        De Bilt
+#
 # STN,YYYYMMDD,DDVEC,FHVEC,FG,FHX,FHXH,FHN,FHNH,FXX,FXXH,TG,TN,TNH,TX,TXH,T10N,T10NH,SQ,SP,Q,DR,RH,RHX,RHXH,PG,PX,PXH,PN,PNH,VVN,VVNH,VVX,VVXH,NG,UG,UX,UXH,UN,UNH,EV24
   260,20240101,  230,   45,   52,   72,   15,   31,    1,  100,   15,   85,   62,    6,  102,   14,   52,    6,   25,   28, 380,   10,   32,    8,   12,10250,10280,   12,10220,    6,   54,    7,   75,   15,    6,   88,   96,    7,   78,   14,    8
   260,20240102,  180,   38,   45,   65,   10,   25,    5,   90,   10,   90,   70,    3,  110,   15,   60,    3,   30,   35, 400,    5,   20,    5,    8,10260,10290,   10,10230,    5,   50,    5,   80,   12,    5,   85,   92,    5,   75,   12,   10
   260,20240103,  210,   42,   48,   68,   12,   28,    3,   95,   12,   88,   65,    5,  105,   14,   55,    5,   28,   32, 390,    8,   28,    7,   10,10255,10285,   11,10225,    6,   52,    6,   78,   14,    5,   86,   94,    6,   76,   13,    9
 `
 
-	// Create a zip file containing the CSV data
+	return createZipServer(t, "etmgeg_260.txt", csvData)
+}
+
+// createZipServer creates a test server that serves a zip archive holding
+// a single file with the given name and content.
+func createZipServer(t *testing.T, filename, content string) *httptest.Server {
+	t.Helper()
+
+	data := createZipArchive(t, filename, content)
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/zip")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(data)
+	}))
+}
+
+// createZipArchive creates a zip archive holding a single file with the
+// given name and content.
+func createZipArchive(t *testing.T, filename, content string) []byte {
+	t.Helper()
+	return createZipArchiveFiles(t, map[string]string{filename: content})
+}
+
+// createZipArchiveFiles creates a zip archive holding the given files.
+func createZipArchiveFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
 	var buf bytes.Buffer
 	w := zip.NewWriter(&buf)
-	f, err := w.Create("etmgeg_260.txt")
-	if err != nil {
-		t.Fatalf("failed to create zip entry: %v", err)
-	}
-	if _, err := f.Write([]byte(csvData)); err != nil {
-		t.Fatalf("failed to write to zip: %v", err)
+	for filename, content := range files {
+		f, err := w.Create(filename)
+		if err != nil {
+			t.Fatalf("failed to create zip entry: %v", err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write to zip: %v", err)
+		}
 	}
 	if err := w.Close(); err != nil {
 		t.Fatalf("failed to close zip: %v", err)
 	}
 
-	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		rw.Header().Set("Content-Type", "application/zip")
-		rw.WriteHeader(http.StatusOK)
-		rw.Write(buf.Bytes())
-	}))
+	return buf.Bytes()
 }
 
-// applyMigrations runs the actual migrations.
+// applyMigrations runs the project's migrations, or those in migrationsDir if set.
 func applyMigrations(t *testing.T, database *sql.DB, migrationsDir string) {
 	t.Helper()
 
-	// Create migrations table
-	_, err := database.Exec(`
-		CREATE TABLE IF NOT EXISTS migrations (
-			id SERIAL PRIMARY KEY,
-			version INTEGER NOT NULL UNIQUE,
-			filename VARCHAR(255) NOT NULL,
-			applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
-		t.Fatalf("failed to create migrations table: %v", err)
-	}
-
-	// Create weather_records table
-	_, err = database.Exec(`
-		CREATE TABLE IF NOT EXISTS weather_records (
-			id SERIAL PRIMARY KEY,
-			station_id INTEGER NOT NULL,
-			date DATE NOT NULL,
-			ddvec INTEGER,
-			fhvec INTEGER,
-			fg INTEGER,
-			fhx INTEGER,
-			fhxh INTEGER,
-			fhn INTEGER,
-			fhnh INTEGER,
-			fxx INTEGER,
-			fxxh INTEGER,
-			tg INTEGER,
-			tn INTEGER,
-			tnh INTEGER,
-			tx INTEGER,
-			txh INTEGER,
-			t10n INTEGER,
-			t10nh INTEGER,
-			sq INTEGER,
-			sp INTEGER,
-			q INTEGER,
-			dr INTEGER,
-			rh INTEGER,
-			rhx INTEGER,
-			rhxh INTEGER,
-			pg INTEGER,
-			px INTEGER,
-			pxh INTEGER,
-			pn INTEGER,
-			pnh INTEGER,
-			vvn INTEGER,
-			vvnh INTEGER,
-			vvx INTEGER,
-			vvxh INTEGER,
-			ng INTEGER,
-			ug INTEGER,
-			ux INTEGER,
-			uxh INTEGER,
-			un INTEGER,
-			unh INTEGER,
-			ev24 INTEGER,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE (station_id, date)
-		)
-	`)
-	if err != nil {
-		t.Fatalf("failed to create weather_records table: %v", err)
+	if migrationsDir == "" {
+		migrationsDir = filepath.Join("..", "..", "migrations")
+	}
+
+	runner := migration.NewRunner(database, nil)
+	if _, err := runner.Run(context.Background(), migrationsDir); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
 	}
 }
 
 func TestSyncCommand(t *testing.T) {
 	databaseURL := getTestDatabaseURL(t)
 
-	database, err := db.Connect(databaseURL)
+	database, err := db.Connect(context.Background(), databaseURL)
 	if err != nil {
 		t.Fatalf("failed to connect to database: %v", err)
 	}
@@ -158,6 +137,17 @@ func TestSyncCommand(t *testing.T) {
 		if count != 3 {
 			t.Errorf("expected 3 records, got %d", count)
 		}
+
+		// Verify station metadata was stored
+		var name string
+		var latitude float64
+		err = database.QueryRow("SELECT name, latitude FROM stations WHERE id = 260").Scan(&name, &latitude)
+		if err != nil {
+			t.Fatalf("failed to query station: %v", err)
+		}
+		if name != "De Bilt" || latitude != 52.1 {
+			t.Errorf("expected De Bilt at latitude 52.1, got %s at %v", name, latitude)
+		}
 	})
 
 	t.Run("skips duplicate records on re-sync", func(t *testing.T) {
@@ -213,3 +203,741 @@ func TestSyncCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestSyncLocalInput(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "etmgeg_260.txt")
+	if err := os.WriteFile(textPath, []byte("# STN,YYYYMMDD,TG\n  260,20240101,   85\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	zipPath := filepath.Join(dir, "etmgeg_260.zip")
+	zipData := createZipArchive(t, "etmgeg_260.txt", "# STN,YYYYMMDD,TG\n  260,20240102,   90\n")
+	if err := os.WriteFile(zipPath, zipData, 0o644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	stdinPath := filepath.Join(dir, "stdin.zip")
+	stdinData := createZipArchive(t, "etmgeg_260.txt", "# STN,YYYYMMDD,TG\n  260,20240103,   95\n")
+	if err := os.WriteFile(stdinPath, stdinData, 0o644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		args          []string
+		stdin         string
+		expectedCount int
+	}{
+		{name: "extracted text file", args: []string{"--input", textPath}, expectedCount: 1},
+		{name: "zip archive via file URL", args: []string{"--url", "file://" + filepath.ToSlash(zipPath)}, expectedCount: 2},
+		{name: "zip archive from standard input", args: []string{"--input", "-"}, stdin: stdinPath, expectedCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stdin != "" {
+				f, err := os.Open(tt.stdin)
+				if err != nil {
+					t.Fatalf("failed to open stdin file: %v", err)
+				}
+				defer f.Close()
+
+				stdin := os.Stdin
+				os.Stdin = f
+				defer func() { os.Stdin = stdin }()
+			}
+
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs(append([]string{"sync"}, tt.args...))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			var count int
+			if err := database.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count); err != nil {
+				t.Fatalf("failed to count records: %v", err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("expected %d records, got %d", tt.expectedCount, count)
+			}
+		})
+	}
+
+	// The files were synced without --station, so no station is recorded
+	var stationRuns int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sync_runs WHERE station_id IS NOT NULL").Scan(&stationRuns); err != nil {
+		t.Fatalf("failed to count sync runs: %v", err)
+	}
+	if stationRuns != 0 {
+		t.Errorf("expected sync runs without a station, got %d with one", stationRuns)
+	}
+}
+
+func TestSyncStationMetadataWithoutRecords(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	// A station without observations yet still describes itself in the header
+	textPath := filepath.Join(t.TempDir(), "etmgeg_240.txt")
+	content := `# STN         LON(east)   LAT(north)  ALT(m)      NAME
+# 240         4.790       52.318      -3.30       Schiphol
+#
+# STN,YYYYMMDD,TG
+`
+	if err := os.WriteFile(textPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	cmd := cli.NewRootCommand()
+	cmd.SetArgs([]string{"sync", "--input", textPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	var name string
+	if err := database.QueryRow("SELECT name FROM stations WHERE id = 240").Scan(&name); err != nil {
+		t.Fatalf("failed to get station: %v", err)
+	}
+	if name != "Schiphol" {
+		t.Errorf("expected station name Schiphol, got %q", name)
+	}
+}
+
+func TestSyncParseMode(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "etmgeg_260.txt")
+	content := "# STN,YYYYMMDD,TG\n  260,20240101,   85\n  260,20240102,  abc\n  260,20240103,   88\n"
+	if err := os.WriteFile(textPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write data file: %v", err)
+	}
+	rejectPath := filepath.Join(dir, "rejects.csv")
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	tests := []struct {
+		name            string
+		args            []string
+		errContains     string
+		expectedCount   int
+		expectedRejects int
+		rejectFile      string
+		runs            int
+	}{
+		{name: "strict fails on the malformed line", errContains: "line 3, column TG"},
+		{name: "lenient records rejects in the database", args: []string{"--parse-mode", "lenient"}, expectedCount: 2, expectedRejects: 1},
+		{
+			name:            "lenient sync again records each reject once",
+			args:            []string{"--parse-mode", "lenient", "--force"},
+			expectedCount:   2,
+			expectedRejects: 1,
+			runs:            2,
+		},
+		{
+			name:          "lenient writes rejects to a file",
+			args:          []string{"--parse-mode", "lenient", "--reject-file", rejectPath},
+			expectedCount: 2,
+			rejectFile:    rejectPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupDatabase(t, database)
+			applyMigrations(t, database, "")
+
+			for run := 0; run < max(tt.runs, 1); run++ {
+				cmd := cli.NewRootCommand()
+				cmd.SetArgs(append([]string{"sync", "--input", textPath}, tt.args...))
+				err := cmd.Execute()
+				if tt.errContains != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+						t.Fatalf("expected error containing %q, got %v", tt.errContains, err)
+					}
+				} else if err != nil {
+					t.Fatalf("sync failed: %v", err)
+				}
+			}
+
+			var count, rejects int
+			if err := database.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count); err != nil {
+				t.Fatalf("failed to count records: %v", err)
+			}
+			if err := database.QueryRow("SELECT COUNT(*) FROM parse_rejects").Scan(&rejects); err != nil {
+				t.Fatalf("failed to count rejects: %v", err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("expected %d records, got %d", tt.expectedCount, count)
+			}
+			if rejects != tt.expectedRejects {
+				t.Errorf("expected %d rejects in the database, got %d", tt.expectedRejects, rejects)
+			}
+
+			if tt.rejectFile != "" {
+				data, err := os.ReadFile(tt.rejectFile)
+				if err != nil {
+					t.Fatalf("failed to read reject file: %v", err)
+				}
+				if !strings.Contains(string(data), "260,20240102,  abc") {
+					t.Errorf("expected reject file to hold the malformed line, got %q", data)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncMultiFileArchive(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+
+	zipPath := filepath.Join(t.TempDir(), "etmgeg.zip")
+	zipData := createZipArchiveFiles(t, map[string]string{
+		"etmgeg_260.txt": "# STN,YYYYMMDD,TG\n  260,20240101,   85\n  260,20240102,   90\n",
+		"etmgeg_344.txt": "# STN,YYYYMMDD,TG\n  344,20240101,   75\n",
+		"README.txt.bak": "not a data file",
+	})
+	if err := os.WriteFile(zipPath, zipData, 0o644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[int]int
+		wantErr  bool
+	}{
+		{
+			name:     "selected data file",
+			args:     []string{"--entry", "etmgeg_344.txt", "--station", "344"},
+			expected: map[int]int{344: 1},
+		},
+		{
+			name:     "every data file",
+			expected: map[int]int{260: 2, 344: 1},
+		},
+		{
+			name:     "data file of another station",
+			args:     []string{"--entry", "etmgeg_344.txt", "--station", "260"},
+			expected: map[int]int{},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid station number",
+			args:     []string{"--station", "0"},
+			expected: map[int]int{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanupDatabase(t, database)
+			applyMigrations(t, database, "")
+
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs(append([]string{"sync", "--input", zipPath}, tt.args...))
+			err := cmd.Execute()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected sync to fail")
+				}
+			} else if err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			rows, err := database.Query("SELECT station_id, COUNT(*) FROM weather_records GROUP BY station_id")
+			if err != nil {
+				t.Fatalf("failed to count records: %v", err)
+			}
+			defer rows.Close()
+
+			counts := map[int]int{}
+			for rows.Next() {
+				var station, count int
+				if err := rows.Scan(&station, &count); err != nil {
+					t.Fatalf("failed to scan count: %v", err)
+				}
+				counts[station] = count
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("failed to read counts: %v", err)
+			}
+
+			if len(counts) != len(tt.expected) {
+				t.Errorf("expected records for %d stations, got %v", len(tt.expected), counts)
+			}
+			for station, want := range tt.expected {
+				if counts[station] != want {
+					t.Errorf("station %d: expected %d records, got %d", station, want, counts[station])
+				}
+			}
+		})
+	}
+}
+
+func TestSyncConditionalDownload(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	data := createZipArchive(t, "etmgeg_260.txt", `# STN,YYYYMMDD,TG
+  260,20240101,   85
+`)
+	etag := `"v1"`
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		rw.Header().Set("ETag", etag)
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(data)
+	}))
+	defer server.Close()
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	tests := []struct {
+		name              string
+		args              []string
+		expectedDownloads int
+	}{
+		{name: "first sync downloads the archive", expectedDownloads: 1},
+		{name: "unchanged archive is not downloaded", expectedDownloads: 1},
+		{name: "force downloads unchanged archive", args: []string{"--force"}, expectedDownloads: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs(append([]string{"sync", "--url", server.URL}, tt.args...))
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			if downloads != tt.expectedDownloads {
+				t.Errorf("expected %d downloads, got %d", tt.expectedDownloads, downloads)
+			}
+		})
+	}
+
+	var stored string
+	if err := database.QueryRow("SELECT etag FROM sources WHERE url = $1", server.URL).Scan(&stored); err != nil {
+		t.Fatalf("failed to query source: %v", err)
+	}
+	if stored != etag {
+		t.Errorf("expected stored etag %s, got %s", etag, stored)
+	}
+}
+
+func TestSyncInterrupted(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first station syncs; the download of the second one is
+	// interrupted as if by Ctrl-C
+	data := createZipArchive(t, "etmgeg_260.txt", `# STN,YYYYMMDD,TG
+  260,20240101,   85
+  260,20240102,   90
+`)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "344") {
+			cancel()
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write(data)
+	}))
+	defer server.Close()
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	cmd := cli.NewRootCommand()
+	cmd.SetArgs([]string{"sync", "--url", server.URL + "/etmgeg_{station}.zip", "--station", "260", "--station", "344"})
+	err = cmd.ExecuteContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected sync to be interrupted, got %v", err)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected the 2 records of the completed station, got %d", count)
+	}
+
+	var runs int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sync_runs WHERE station_id = 344").Scan(&runs); err != nil {
+		t.Fatalf("failed to count sync runs: %v", err)
+	}
+	if runs != 0 {
+		t.Errorf("expected no sync run for the interrupted station, got %d", runs)
+	}
+}
+
+func TestSyncVerifiesArchive(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	data := createZipArchive(t, "etmgeg_260.txt", `# STN,YYYYMMDD,TG
+  260,20240101,   85
+`)
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etmgeg_260.zip", "/unsigned.zip":
+			rw.Write(data)
+		case "/etmgeg_260.zip.sha256":
+			fmt.Fprintf(rw, "%s  etmgeg_260.zip\n", hash)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer server.Close()
+	archiveURL := server.URL + "/etmgeg_260.zip"
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "pinned hash mismatch", args: []string{"--sha256", strings.Repeat("0", 64)}, wantErr: true},
+		{name: "pinned hash matches", args: []string{"--sha256", hash}},
+		{name: "sidecar checksum matches", args: []string{"--checksum-sidecar"}},
+		{name: "missing sidecar checksum", args: []string{"--checksum-sidecar", "--url", server.URL + "/unsigned.zip"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs(append([]string{"sync", "--url", archiveURL}, tt.args...))
+			err := cmd.Execute()
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+		})
+	}
+
+	rows, err := database.Query("SELECT source_sha256, records_read FROM sync_runs WHERE source_url = $1 ORDER BY id", archiveURL)
+	if err != nil {
+		t.Fatalf("failed to query sync runs: %v", err)
+	}
+	defer rows.Close()
+
+	runs := 0
+	for rows.Next() {
+		var storedHash string
+		var recordsRead int
+		if err := rows.Scan(&storedHash, &recordsRead); err != nil {
+			t.Fatalf("failed to scan sync run: %v", err)
+		}
+		if storedHash != hash {
+			t.Errorf("expected recorded hash %s, got %s", hash, storedHash)
+		}
+		if recordsRead != 1 {
+			t.Errorf("expected 1 record read, got %d", recordsRead)
+		}
+		runs++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read sync runs: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("expected 2 recorded sync runs, got %d", runs)
+	}
+}
+
+func TestSyncOpenDataSource(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	data := createZipArchive(t, "etmgeg_260.txt", `# STN,YYYYMMDD,TG
+  260,20240101,   85
+  260,20240102,   90
+`)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/datasets/daily/versions/1/files/etmgeg_260.zip/url":
+			if r.Header.Get("Authorization") != "secret" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(rw, `{"temporaryDownloadUrl": %q}`, server.URL+"/signed/etmgeg_260.zip")
+		case "/signed/etmgeg_260.zip":
+			rw.Write(data)
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	os.Setenv("KNMI_OPENDATA_URL", server.URL+"/v1")
+	os.Setenv("KNMI_OPENDATA_DATASET", "daily")
+	defer os.Unsetenv("DATABASE_URL")
+	defer os.Unsetenv("KNMI_OPENDATA_URL")
+	defer os.Unsetenv("KNMI_OPENDATA_DATASET")
+
+	tests := []struct {
+		name          string
+		apiKey        string
+		wantErr       bool
+		expectedCount int
+	}{
+		{name: "missing API key", wantErr: true},
+		{name: "rejected API key", apiKey: "wrong", wantErr: true},
+		{name: "syncs from the API", apiKey: "secret", expectedCount: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("KNMI_API_KEY", tt.apiKey)
+			defer os.Unsetenv("KNMI_API_KEY")
+
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs([]string{"sync", "--source", "opendata", "--station", "260"})
+			err := cmd.Execute()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			var count int
+			if err := database.QueryRow("SELECT COUNT(*) FROM weather_records").Scan(&count); err != nil {
+				t.Fatalf("failed to count records: %v", err)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("expected %d records, got %d", tt.expectedCount, count)
+			}
+		})
+	}
+}
+
+// TestSyncSourceConflicts checks flag combinations rejected before any
+// data is fetched, so it needs no database.
+func TestSyncSourceConflicts(t *testing.T) {
+	os.Setenv("KNMI_API_KEY", "secret")
+	defer os.Unsetenv("KNMI_API_KEY")
+
+	tests := []struct {
+		name        string
+		args        []string
+		errContains string
+	}{
+		{
+			name:        "open data source with hourly data",
+			args:        []string{"--source", "opendata", "--resolution", "hourly"},
+			errContains: "only serves daily weather data",
+		},
+		{
+			name:        "open data source with precipitation data",
+			args:        []string{"--source", "opendata", "--dataset", "precipitation"},
+			errContains: "only serves daily weather data",
+		},
+		{
+			name:        "source with a URL",
+			args:        []string{"--source", "opendata", "--url", "https://example.com/etmgeg_{station}.zip"},
+			errContains: "--source and --url cannot be combined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := cli.NewRootCommand()
+			cmd.SetArgs(append([]string{"sync", "--dry-run"}, tt.args...))
+			err := cmd.Execute()
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+			}
+		})
+	}
+}
+
+func TestSyncHourlyCommand(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	csvData := `# STN,YYYYMMDD,   HH,   DD,   FH,   FF,   FX,    T, T10N,   TD,   SQ,    Q,   DR,   RH,    P,   VV,    N,    U,   WW,   IX,    M,    R,    S,    O,    Y
+  260,20240101,    1,  220,   60,   60,  100,   82,     ,   71,    0,    0,    0,    0,10012,   65,    8,   93,     ,    5,    0,    0,    0,    0,    0
+  260,20240101,    2,  230,   70,   70,  120,   80,     ,   70,    0,    0,    4,   -1,10010,   60,    8,   93,   21,    7,    0,    1,    0,    0,    0
+`
+	server := createZipServer(t, "uurgeg_260_2021-2030.txt", csvData)
+	defer server.Close()
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	// Sync twice to verify duplicates are skipped
+	for i := 0; i < 2; i++ {
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs([]string{"sync", "--resolution", "hourly", "--url", server.URL})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("hourly sync %d failed: %v", i+1, err)
+		}
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM hourly_records").Scan(&count); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 hourly records, got %d", count)
+	}
+}
+
+func TestSyncPrecipitationCommand(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+
+	csvData := `STN,YYYYMMDD,   RD,   SX,
+
+  550,20240101,   32,     ,
+  550,20240102,    0,    1,
+  550,20240103,   -1,     ,
+`
+	server := createZipServer(t, "neerslaggeg_550.txt", csvData)
+	defer server.Close()
+
+	os.Setenv("DATABASE_URL", databaseURL)
+	defer os.Unsetenv("DATABASE_URL")
+
+	for i := 0; i < 2; i++ {
+		cmd := cli.NewRootCommand()
+		cmd.SetArgs([]string{"sync", "--dataset", "precipitation", "--station", "550", "--url", server.URL})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("precipitation sync %d failed: %v", i+1, err)
+		}
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM precipitation_records").Scan(&count); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 precipitation records, got %d", count)
+	}
+}
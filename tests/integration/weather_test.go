@@ -0,0 +1,251 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+	_ "github.com/lib/pq"
+)
+
+// generateRecords creates count consecutive daily records for a station.
+func generateRecords(stationID, count int, start time.Time) []parser.WeatherRecord {
+	records := make([]parser.WeatherRecord, count)
+	for i := range records {
+		tg := 100 + i%50
+		records[i] = parser.WeatherRecord{
+			StationID: stationID,
+			Date:      start.AddDate(0, 0, i),
+			TG:        &tg,
+		}
+	}
+	return records
+}
+
+func TestInsertRecordsBulk(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+	if err := db.NewStationRepository(database).EnsureStations(context.Background(), []int{260}); err != nil {
+		t.Fatalf("failed to register station: %v", err)
+	}
+
+	repo := db.NewWeatherRepository(database)
+	start := time.Date(1901, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := generateRecords(260, db.BulkInsertThreshold+250, start)
+
+	t.Run("inserts all records with COPY", func(t *testing.T) {
+		result, err := repo.InsertRecords(context.Background(), records)
+		if err != nil {
+			t.Fatalf("bulk insert failed: %v", err)
+		}
+		if result.Inserted != len(records) || result.Skipped != 0 {
+			t.Errorf("expected %d inserted and 0 skipped, got %+v", len(records), result)
+		}
+	})
+
+	t.Run("skips existing records with COPY", func(t *testing.T) {
+		// Overlap half of the existing records with new ones
+		overlap := generateRecords(260, len(records), start.AddDate(0, 0, len(records)/2))
+
+		result, err := repo.InsertRecords(context.Background(), overlap)
+		if err != nil {
+			t.Fatalf("bulk insert failed: %v", err)
+		}
+
+		expectedSkipped := len(records) - len(records)/2
+		if result.Skipped != expectedSkipped || result.Inserted != len(overlap)-expectedSkipped {
+			t.Errorf("expected %d skipped, got %+v", expectedSkipped, result)
+		}
+	})
+
+	t.Run("copies several batches in one transaction", func(t *testing.T) {
+		ctx := context.Background()
+		tx, err := repo.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		next := start.AddDate(0, 0, 2*len(records))
+		batches := [][]parser.WeatherRecord{
+			generateRecords(260, len(records), next),
+			generateRecords(260, len(records), next.AddDate(0, 0, len(records))),
+		}
+		for i, batch := range batches {
+			result, err := repo.InsertRecordsTx(ctx, tx, batch)
+			if err != nil {
+				t.Fatalf("bulk insert of batch %d failed: %v", i+1, err)
+			}
+			if result.Inserted != len(batch) || result.Skipped != 0 {
+				t.Errorf("batch %d: expected %d inserted and 0 skipped, got %+v", i+1, len(batch), result)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+	})
+}
+
+func TestUpsertRecords(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+	if err := db.NewStationRepository(database).EnsureStations(context.Background(), []int{260}); err != nil {
+		t.Fatalf("failed to register station: %v", err)
+	}
+
+	repo := db.NewWeatherRepository(database)
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	records := generateRecords(260, 5, start)
+	if _, err := repo.InsertRecords(context.Background(), records); err != nil {
+		t.Fatalf("initial insert failed: %v", err)
+	}
+
+	// Revise one value and add one new day
+	revised := generateRecords(260, 6, start)
+	tg := 999
+	revised[2].TG = &tg
+
+	result, err := repo.UpsertRecords(context.Background(), revised)
+	if err != nil {
+		t.Fatalf("upsert failed: %v", err)
+	}
+	if result.Inserted != 1 || result.Updated != 1 || result.Skipped != 4 {
+		t.Errorf("expected 1 inserted, 1 updated, 4 skipped, got %+v", result)
+	}
+
+	var stored int
+	err = database.QueryRow("SELECT tg FROM weather_records WHERE station_id = 260 AND date = $1", revised[2].Date).Scan(&stored)
+	if err != nil {
+		t.Fatalf("failed to query revised record: %v", err)
+	}
+	if stored != tg {
+		t.Errorf("expected revised tg=%d, got %d", tg, stored)
+	}
+
+	revisions, err := repo.GetRevisions(context.Background(), 260, revised[2].Date)
+	if err != nil {
+		t.Fatalf("failed to get revisions: %v", err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("expected 1 revision, got %d", len(revisions))
+	}
+	if cols := revisions[0].ChangedColumns; len(cols) != 1 || cols[0] != "tg" {
+		t.Errorf("expected changed columns [tg], got %v", cols)
+	}
+	if prev := revisions[0].Previous["tg"]; prev == nil || *prev != *records[2].TG {
+		t.Errorf("expected previous tg=%d, got %v", *records[2].TG, prev)
+	}
+}
+
+func TestFilterNewRecords(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+	if err := db.NewStationRepository(database).EnsureStations(context.Background(), []int{260, 344}); err != nil {
+		t.Fatalf("failed to register stations: %v", err)
+	}
+
+	repo := db.NewWeatherRepository(database)
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.InsertRecords(context.Background(), generateRecords(260, 5, start)); err != nil {
+		t.Fatalf("initial insert failed: %v", err)
+	}
+
+	// Days 3 to 7 of station 260 overlap the stored days 3 to 5; station
+	// 344 has no records yet
+	batch := append(generateRecords(260, 5, start.AddDate(0, 0, 2)), generateRecords(344, 2, start)...)
+	filtered, err := repo.FilterNewRecords(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("filter failed: %v", err)
+	}
+
+	var keys []string
+	for _, rec := range filtered {
+		keys = append(keys, fmt.Sprintf("%d:%s", rec.StationID, rec.Date.Format("2006-01-02")))
+	}
+	expected := "260:2024-07-06,260:2024-07-07,344:2024-07-01,344:2024-07-02"
+	if got := strings.Join(keys, ","); got != expected {
+		t.Errorf("expected new records %s, got %s", expected, got)
+	}
+}
+
+func TestTracePrecipitation(t *testing.T) {
+	databaseURL := getTestDatabaseURL(t)
+
+	database, err := db.Connect(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	cleanupDatabase(t, database)
+	t.Cleanup(func() { cleanupDatabase(t, database) })
+	applyMigrations(t, database, "")
+	if err := db.NewStationRepository(database).EnsureStations(context.Background(), []int{260}); err != nil {
+		t.Fatalf("failed to register station: %v", err)
+	}
+
+	input := `# STN,YYYYMMDD,   RH,  RHX
+  260,20240101,   -1,   -1
+  260,20240102,   12,    8
+  260,20240103,   -1,   -1
+  260,20240104,    0,    0
+`
+	records, err := parser.ParseCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to parse records: %v", err)
+	}
+
+	repo := db.NewWeatherRepository(database)
+	if _, err := repo.InsertRecords(context.Background(), records); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	var total, traceDays int
+	err = database.QueryRow("SELECT SUM(rh), COUNT(*) FILTER (WHERE rh_trace) FROM weather_records").Scan(&total, &traceDays)
+	if err != nil {
+		t.Fatalf("failed to aggregate precipitation: %v", err)
+	}
+	if total != 12 || traceDays != 2 {
+		t.Errorf("expected sum 12 with 2 trace days, got sum %d with %d trace days", total, traceDays)
+	}
+
+	stored, err := repo.GetRecord(context.Background(), 260, records[0].Date)
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+	if stored == nil || !stored.RHTrace || !stored.RHXTrace || stored.DRTrace {
+		t.Errorf("expected rh and rhx trace flags to be stored, got %+v", stored)
+	}
+}
@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+func TestCache(t *testing.T) {
+	const url = "https://example.com/data/etmgeg_260.zip"
+	first := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		stores       []string
+		expected     string
+		expectedTime string
+		wantErr      bool
+	}{
+		{
+			name:    "nothing cached",
+			wantErr: true,
+		},
+		{
+			name:         "newest copy wins",
+			stores:       []string{"old", "new"},
+			expected:     "new",
+			expectedTime: "20240701T120100Z",
+		},
+		{
+			name:         "identical content is stored once",
+			stores:       []string{"same", "same"},
+			expected:     "same",
+			expectedTime: "20240701T120100Z",
+		},
+		{
+			name:         "content downloaded again is the newest copy",
+			stores:       []string{"a", "b", "a"},
+			expected:     "a",
+			expectedTime: "20240701T120200Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := fetch.NewCache(t.TempDir())
+			for i, content := range tt.stores {
+				at := first.Add(time.Duration(i) * time.Minute)
+				if _, err := cache.Store(url, strings.NewReader(content), at); err != nil {
+					t.Fatalf("store failed: %v", err)
+				}
+			}
+
+			path, err := cache.Latest(url)
+			if tt.wantErr {
+				if !errors.Is(err, fetch.ErrNotCached) {
+					t.Errorf("expected ErrNotCached, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if content, err := os.ReadFile(path); err != nil || string(content) != tt.expected {
+				t.Errorf("expected newest copy %q, got %q (%v)", tt.expected, content, err)
+			}
+			if !strings.HasPrefix(filepath.Base(path), tt.expectedTime+"-") {
+				t.Errorf("expected file stored at %s, got %s", tt.expectedTime, path)
+			}
+			matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.zip"))
+			if len(matches) != len(uniqueStrings(tt.stores)) {
+				t.Errorf("expected %d cached files, got %d", len(uniqueStrings(tt.stores)), len(matches))
+			}
+		})
+	}
+}
+
+func TestClientCachesDownloads(t *testing.T) {
+	data := createTestZip(t, map[string]string{"etmgeg_260.txt": "test content"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	cache := fetch.NewCache(t.TempDir())
+	client := fetch.NewClient(0, 0)
+	client.Cache = cache
+
+	archive, err := client.Open(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if _, err := cache.Latest(server.URL); !errors.Is(err, fetch.ErrNotCached) {
+		t.Errorf("expected archive not to be cached before it is saved, got %v", err)
+	}
+	if err := archive.SaveToCache(); err != nil {
+		t.Fatalf("saving to cache failed: %v", err)
+	}
+	archive.Close()
+
+	path, err := cache.Latest(server.URL)
+	if err != nil {
+		t.Fatalf("expected cached archive: %v", err)
+	}
+
+	cached, err := fetch.OpenFile(path)
+	if err != nil {
+		t.Fatalf("opening cached archive failed: %v", err)
+	}
+	defer cached.Close()
+
+	content, err := io.ReadAll(cached)
+	if err != nil {
+		t.Fatalf("reading cached archive failed: %v", err)
+	}
+	if string(content) != "test content" {
+		t.Errorf("expected %q, got %q", "test content", content)
+	}
+}
+
+func TestClientDoesNotCacheCorruptDownloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PK\x03\x04 truncated"))
+	}))
+	defer server.Close()
+
+	cache := fetch.NewCache(t.TempDir())
+	client := fetch.NewClient(0, 0)
+	client.Cache = cache
+
+	if _, err := client.Open(context.Background(), server.URL); err == nil {
+		t.Fatal("expected open of a corrupt archive to fail")
+	}
+	if _, err := cache.Latest(server.URL); !errors.Is(err, fetch.ErrNotCached) {
+		t.Errorf("expected corrupt archive not to be cached, got %v", err)
+	}
+}
+
+// uniqueStrings returns the distinct values of s.
+func uniqueStrings(s []string) map[string]bool {
+	unique := make(map[string]bool)
+	for _, v := range s {
+		unique[v] = true
+	}
+	return unique
+}
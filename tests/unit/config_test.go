@@ -0,0 +1,122 @@
+package unit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/config"
+)
+
+func TestParseStations(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    []int
+		expectError bool
+	}{
+		{"single station", "260", []int{260}, false},
+		{"multiple stations", "240,260,344", []int{240, 260, 344}, false},
+		{"whitespace tolerated", " 240 , 380 ", []int{240, 380}, false},
+		{"duplicates removed", "260,240,260", []int{260, 240}, false},
+		{"empty entries ignored", "260,,344,", []int{260, 344}, false},
+		{"empty input", "", nil, true},
+		{"non-numeric station", "260,abc", nil, true},
+		{"negative station", "-260", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stations, err := config.ParseStations(tc.input)
+
+			if tc.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(stations, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, stations)
+			}
+		})
+	}
+}
+
+func TestStationURL(t *testing.T) {
+	testCases := []struct {
+		template string
+		station  int
+		expected string
+	}{
+		{config.DefaultKNMIDataURL, 240, "https://cdn.knmi.nl/knmi/map/page/klimatologie/gegevens/daggegevens/etmgeg_240.zip"},
+		{"http://mirror/{station}/etmgeg_{station}.zip", 380, "http://mirror/380/etmgeg_380.zip"},
+		{"http://mirror/etmgeg.zip", 260, "http://mirror/etmgeg.zip"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			if got := config.StationURL(tc.template, tc.station); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHasStationPlaceholder(t *testing.T) {
+	if !config.HasStationPlaceholder(config.DefaultKNMIDataURL) {
+		t.Error("expected default URL to contain the station placeholder")
+	}
+	if config.HasStationPlaceholder("http://example.com/etmgeg_260.zip") {
+		t.Error("expected concrete URL not to contain the station placeholder")
+	}
+}
+
+func TestDecade(t *testing.T) {
+	testCases := []struct {
+		year     int
+		expected string
+	}{
+		{2024, "2021-2030"},
+		{2021, "2021-2030"},
+		{2030, "2021-2030"},
+		{2020, "2011-2020"},
+		{1951, "1951-1960"},
+	}
+
+	for _, tc := range testCases {
+		if got := config.Decade(tc.year); got != tc.expected {
+			t.Errorf("Decade(%d): expected %q, got %q", tc.year, tc.expected, got)
+		}
+	}
+}
+
+func TestDecades(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from, to int
+		expected []string
+	}{
+		{"same decade", 2022, 2026, []string{"2021-2030"}},
+		{"decade boundary", 2020, 2021, []string{"2011-2020", "2021-2030"}},
+		{"several decades", 1995, 2024, []string{"1991-2000", "2001-2010", "2011-2020", "2021-2030"}},
+		{"from after to", 2030, 2020, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := config.Decades(tc.from, tc.to); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecadeURL(t *testing.T) {
+	url := config.DecadeURL(config.StationURL(config.DefaultKNMIHourlyURL, 260), "2021-2030")
+	expected := "https://cdn.knmi.nl/knmi/map/page/klimatologie/gegevens/uurgegevens/uurgeg_260_2021-2030.zip"
+	if url != expected {
+		t.Errorf("expected %q, got %q", expected, url)
+	}
+}
@@ -0,0 +1,282 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+// abortAfter writes the first n bytes of content with the full
+// Content-Length and then drops the connection, like an interrupted download.
+func abortAfter(w http.ResponseWriter, content string, n int) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(content[:n]))
+	w.(http.Flusher).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+func TestClientDownload(t *testing.T) {
+	const content = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	tests := []struct {
+		name         string
+		retries      int
+		timeout      time.Duration
+		handler      func(attempt int32, w http.ResponseWriter, r *http.Request)
+		wantAttempts int32
+		wantErr      bool
+		errContains  string
+	}{
+		{
+			name:    "retries server errors",
+			retries: 3,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				if attempt < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(content))
+			},
+			wantAttempts: 3,
+		},
+		{
+			name:    "gives up after the last retry",
+			retries: 2,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantAttempts: 3,
+			wantErr:      true,
+			errContains:  "giving up after 3 attempts",
+		},
+		{
+			name:    "does not retry client errors",
+			retries: 3,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+			errContains:  "404",
+		},
+		{
+			name:    "resumes interrupted download with range request",
+			retries: 1,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				if attempt == 1 {
+					w.Header().Set("ETag", `"v1"`)
+					abortAfter(w, content, 10)
+				}
+				if r.Header.Get("Range") != "bytes=10-" || r.Header.Get("If-Range") != `"v1"` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(content)-1, len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[10:]))
+			},
+			wantAttempts: 2,
+		},
+		{
+			name:    "restarts when server ignores range",
+			retries: 1,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				if attempt == 1 {
+					abortAfter(w, content, 10)
+				}
+				w.Write([]byte(content))
+			},
+			wantAttempts: 2,
+		},
+		{
+			name:    "restarts without validators instead of resuming",
+			retries: 1,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				// The file changes between attempts; a resumed download
+				// would append the new tail to the old head
+				if attempt == 1 {
+					abortAfter(w, strings.ToUpper(content), 10)
+				}
+				if r.Header.Get("Range") != "" {
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(content)-1, len(content)))
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write([]byte(content[10:]))
+					return
+				}
+				w.Write([]byte(content))
+			},
+			wantAttempts: 2,
+		},
+		{
+			name:    "restarts after a partial response to a full request",
+			retries: 1,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				if attempt == 1 {
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-9/%d", len(content)))
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write([]byte(content[:10]))
+					return
+				}
+				w.Write([]byte(content))
+			},
+			wantAttempts: 2,
+		},
+		{
+			name:    "retries after timeout",
+			retries: 1,
+			timeout: 50 * time.Millisecond,
+			handler: func(attempt int32, w http.ResponseWriter, r *http.Request) {
+				if attempt == 1 {
+					time.Sleep(200 * time.Millisecond)
+				}
+				w.Write([]byte(content))
+			},
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(attempts.Add(1), w, r)
+			}))
+			defer server.Close()
+
+			client := fetch.NewClient(tt.timeout, tt.retries)
+			client.MinBackoff = time.Millisecond
+			client.MaxBackoff = 5 * time.Millisecond
+
+			data, err := client.Download(context.Background(), server.URL)
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != content {
+				t.Errorf("expected %q, got %q", content, data)
+			}
+		})
+	}
+}
+
+func TestClientOpenIfModified(t *testing.T) {
+	data := createTestZip(t, map[string]string{"etmgeg_260.txt": "test content"})
+	const etag = `"v2"`
+	const lastModified = "Mon, 01 Jul 2024 00:00:00 GMT"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		since           fetch.Validators
+		wantNotModified bool
+	}{
+		{name: "no previous download", since: fetch.Validators{}},
+		{name: "changed archive", since: fetch.Validators{ETag: `"v1"`}},
+		{name: "unchanged archive", since: fetch.Validators{ETag: etag}, wantNotModified: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := fetch.NewClient(0, 0).OpenIfModified(context.Background(), server.URL, tt.since)
+
+			if tt.wantNotModified {
+				if !errors.Is(err, fetch.ErrNotModified) {
+					t.Errorf("expected ErrNotModified, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			expected := fetch.Validators{ETag: etag, LastModified: lastModified}
+			if archive.Validators != expected {
+				t.Errorf("expected validators %+v, got %+v", expected, archive.Validators)
+			}
+		})
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	tests := []struct {
+		name         string
+		cancel       func(cancel context.CancelFunc, client *fetch.Client)
+		wantAttempts int32
+	}{
+		{
+			name:         "before the first request",
+			cancel:       func(cancel context.CancelFunc, client *fetch.Client) { cancel() },
+			wantAttempts: 0,
+		},
+		{
+			name: "while waiting to retry",
+			cancel: func(cancel context.CancelFunc, client *fetch.Client) {
+				client.OnRetry = func(attempt int, delay time.Duration, err error) { cancel() }
+			},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			// A backoff far longer than the test shows the wait is cut short
+			client := fetch.NewClient(0, 3)
+			client.MinBackoff = time.Hour
+			client.MaxBackoff = time.Hour
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tt.cancel(cancel, client)
+
+			_, err := client.Download(ctx, server.URL)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, got)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package unit
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -50,7 +52,7 @@ func TestDownload(t *testing.T) {
 			server := httptest.NewServer(tt.handler)
 			defer server.Close()
 
-			data, err := fetch.Download(server.URL)
+			data, err := fetch.Download(context.Background(), server.URL)
 
 			if tt.wantErr {
 				if err == nil {
@@ -142,6 +144,82 @@ func TestExtractZip(t *testing.T) {
 	}
 }
 
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		expected    string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "streams txt file from zip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write(createTestZip(t, map[string]string{
+					"etmgeg_260.txt": "# KNMI data\ntest content",
+				}))
+			},
+			expected: "# KNMI data\ntest content",
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr:     true,
+			errContains: "500",
+		},
+		{
+			name: "invalid zip data",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("not a zip file"))
+			},
+			wantErr:     true,
+			errContains: "zip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			archive, err := fetch.Open(context.Background(), server.URL)
+
+			if tt.wantErr {
+				if err == nil {
+					archive.Close()
+					t.Error("expected error, got nil")
+				} else if tt.errContains != "" && !bytes.Contains([]byte(err.Error()), []byte(tt.errContains)) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("failed to read archive: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, content)
+			}
+			if archive.Size == 0 {
+				t.Error("expected archive size to be set")
+			}
+
+			if err := archive.Close(); err != nil {
+				t.Errorf("failed to close archive: %v", err)
+			}
+		})
+	}
+}
+
 // createTestZip creates a zip archive with the given files.
 func createTestZip(t *testing.T, files map[string]string) []byte {
 	t.Helper()
@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+const hourlyInput = `# BRON: KONINKLIJK NEDERLANDS METEOROLOGISCH INSTITUUT (KNMI)
+# STN,YYYYMMDD,   HH,   DD,   FH,   FF,   FX,    T, T10N,   TD,   SQ,    Q,   DR,   RH,    P,   VV,    N,    U,   WW,   IX,    M,    R,    S,    O,    Y
+#
+  260,20240101,    1,  220,   60,   60,  100,   82,     ,   71,    0,    0,    0,    0,10012,   65,    8,   93,     ,    5,    0,    0,    0,    0,    0
+  260,20240101,    2,  230,   70,   70,  120,   80,     ,   70,    0,    0,    4,   -1,10010,   60,    8,   93,   21,    7,    0,    1,    0,    0,    0
+  260,20240101,   24,  240,   50,   40,   90,   75,   60,   69,    0,    0,    0,    0,10020,   70,    7,   96,     ,    5,    0,    0,    0,    0,    0
+`
+
+func TestParseHourly(t *testing.T) {
+	result, err := parser.ParseHourly(strings.NewReader(hourlyInput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(result.Records))
+	}
+	if len(result.UnknownColumns) != 0 {
+		t.Errorf("expected no unknown columns, got %v", result.UnknownColumns)
+	}
+
+	r := result.Records[1]
+	if r.StationID != 260 || r.Hour != 2 {
+		t.Errorf("expected station 260 hour 2, got station %d hour %d", r.StationID, r.Hour)
+	}
+	if r.T == nil || *r.T != 80 {
+		t.Errorf("expected t=80, got %v", r.T)
+	}
+	if r.WW == nil || *r.WW != 21 {
+		t.Errorf("expected ww=21, got %v", r.WW)
+	}
+	if r.T10N != nil {
+		t.Errorf("expected t10n=nil (empty), got %v", r.T10N)
+	}
+	if r.RH == nil || *r.RH != 0 || !r.RHTrace {
+		t.Errorf("expected trace rh=0, got %v (trace %v)", r.RH, r.RHTrace)
+	}
+	if result.Records[0].RHTrace {
+		t.Error("expected no trace for rh=0")
+	}
+
+	// Hour 24 ends at midnight of the following day
+	last := result.Records[2]
+	expected := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !last.Time().Equal(expected) {
+		t.Errorf("expected time %v, got %v", expected, last.Time())
+	}
+}
+
+func TestParseHourlyErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+	}{
+		{
+			name: "missing hour",
+			input: `# STN,YYYYMMDD,HH,T
+  260,20240101,     ,   82
+`,
+			errContains: "hour",
+		},
+		{
+			name: "header without HH column",
+			input: `# STN,YYYYMMDD,T
+  260,20240101,   82
+`,
+			errContains: "HH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.ParseHourly(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+			}
+		})
+	}
+}
+
+func TestFilterHourlyAfterLatest(t *testing.T) {
+	result, err := parser.ParseHourly(strings.NewReader(hourlyInput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	latest := map[int]time.Time{
+		260: time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC),
+	}
+
+	filtered := db.FilterHourlyAfterLatest(result.Records, latest)
+	if len(filtered) != 1 || filtered[0].Hour != 24 {
+		t.Errorf("expected only the hour 24 record, got %+v", filtered)
+	}
+}
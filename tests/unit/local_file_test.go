@@ -0,0 +1,304 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+func TestOpenFile(t *testing.T) {
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+
+	tests := []struct {
+		name        string
+		data        []byte
+		expected    string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:     "zip archive",
+			data:     createTestZip(t, map[string]string{"etmgeg_260.txt": content}),
+			expected: content,
+		},
+		{
+			name:     "extracted text file",
+			data:     []byte(content),
+			expected: content,
+		},
+		{
+			name:     "empty file",
+			data:     []byte{},
+			expected: "",
+		},
+		{
+			name:        "zip without text file",
+			data:        createTestZip(t, map[string]string{}),
+			wantErr:     true,
+			errContains: "no .txt file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			archive, err := fetch.OpenFile(path)
+			if tt.wantErr {
+				if err == nil {
+					archive.Close()
+					t.Error("expected error, got nil")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			data, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, data)
+			}
+
+			// Local files must survive being closed
+			archive.Close()
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("expected file to be kept: %v", err)
+			}
+		})
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "absolute path", url: "file:///data/etmgeg_260.zip", expected: "/data/etmgeg_260.zip"},
+		{name: "localhost", url: "file://localhost/data/etmgeg_260.zip", expected: "/data/etmgeg_260.zip"},
+		{name: "remote host", url: "file://server/data/etmgeg_260.zip", wantErr: true},
+		{name: "no path", url: "file://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := fetch.FilePath(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got path %q", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path != filepath.FromSlash(tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	url, err := fetch.FileURL("/data/etmgeg_{station}.zip")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "file:///data/etmgeg_{station}.zip" {
+		t.Errorf("expected placeholder to be kept, got %q", url)
+	}
+	if !fetch.IsFileURL(url) {
+		t.Errorf("expected %q to be a file URL", url)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "fragment character", path: "/data/run#2/etmgeg_{station}.zip", expected: "/data/run#2/etmgeg_260.zip"},
+		{name: "query character", path: "/data/what?/etmgeg_{station}.zip", expected: "/data/what?/etmgeg_260.zip"},
+		{name: "percent sign", path: "/data/100%7B/etmgeg_{station}.zip", expected: "/data/100%7B/etmgeg_260.zip"},
+		{name: "space", path: "/data/knmi data/etmgeg_{station}.zip", expected: "/data/knmi data/etmgeg_260.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, err := fetch.FileURL(filepath.FromSlash(tt.path))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			path, err := fetch.FilePath(strings.ReplaceAll(url, "{station}", "260"))
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", url, err)
+			}
+			if path != filepath.FromSlash(tt.expected) {
+				t.Errorf("expected %q, got %q (URL %q)", tt.expected, path, url)
+			}
+		})
+	}
+}
+
+func TestOpenReader(t *testing.T) {
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			name:     "zip stream",
+			data:     createTestZip(t, map[string]string{"etmgeg_260.txt": content}),
+			expected: content,
+		},
+		{
+			name:     "text stream",
+			data:     []byte(content),
+			expected: content,
+		},
+		{
+			name:     "short stream",
+			data:     []byte("PK"),
+			expected: "PK",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := fetch.OpenReader(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			data, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("failed to read stream: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestOpenReaderMaxStreamSize(t *testing.T) {
+	data := createTestZip(t, map[string]string{"etmgeg_260.txt": "  260,20240101,   85\n"})
+
+	tests := []struct {
+		name    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "within limit", limit: int64(len(data))},
+		{name: "limit disabled", limit: -1},
+		{name: "over limit", limit: int64(len(data)) - 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fetch.Client{MaxStreamSize: tt.limit}
+			archive, err := client.OpenReader(bytes.NewReader(data))
+			if tt.wantErr {
+				if !errors.Is(err, fetch.ErrTooLarge) {
+					t.Errorf("expected ErrTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			if archive.Size != int64(len(data)) {
+				t.Errorf("expected size %d, got %d", len(data), archive.Size)
+			}
+			sum := sha256.Sum256(data)
+			if got := archive.SHA256(); got != hex.EncodeToString(sum[:]) {
+				t.Errorf("expected SHA-256 %x, got %s", sum, got)
+			}
+		})
+	}
+}
+
+func TestVerifyStreamedTextMaxStreamSize(t *testing.T) {
+	data := []byte("# STN,YYYYMMDD,TG\n  260,20240101,   85\n")
+	sum := sha256.Sum256(data)
+
+	tests := []struct {
+		name    string
+		limit   int64
+		wantErr bool
+	}{
+		{name: "within limit", limit: int64(len(data))},
+		{name: "limit disabled", limit: -1},
+		{name: "over limit", limit: int64(len(data)) - 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fetch.Client{MaxStreamSize: tt.limit}
+			archive, err := client.OpenReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			err = archive.Verify(hex.EncodeToString(sum[:]))
+			if tt.wantErr {
+				if !errors.Is(err, fetch.ErrTooLarge) {
+					t.Errorf("expected ErrTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if archive.Size != int64(len(data)) {
+				t.Errorf("expected size %d, got %d", len(data), archive.Size)
+			}
+		})
+	}
+}
+
+func TestOpenReaderTextSize(t *testing.T) {
+	data := []byte("# STN,YYYYMMDD,TG\n  260,20240101,   85\n")
+
+	archive, err := fetch.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer archive.Close()
+
+	if _, err := io.ReadAll(archive); err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if archive.Size != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), archive.Size)
+	}
+	sum := sha256.Sum256(data)
+	if got := archive.SHA256(); got != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected SHA-256 %x, got %s", sum, got)
+	}
+}
@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+// openDataStandIn serves a minimal KNMI Open Data API for one dataset, with
+// file listings split into pages of two files.
+type openDataStandIn struct {
+	apiKey string
+	files  []fetch.OpenDataFile
+	data   map[string][]byte
+	server *httptest.Server
+}
+
+func newOpenDataStandIn(t *testing.T, apiKey string, files []fetch.OpenDataFile, data map[string][]byte) *openDataStandIn {
+	t.Helper()
+
+	s := &openDataStandIn{apiKey: apiKey, files: files, data: data}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *openDataStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	// Temporary download URLs are pre-signed and carry no API key
+	if name, ok := strings.CutPrefix(r.URL.Path, "/download/"); ok {
+		if r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, ok := s.data[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+		return
+	}
+
+	if r.Header.Get("Authorization") != s.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v1/datasets/daily/versions/1/files")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if rest == "" {
+		start := 0
+		if token := r.URL.Query().Get("nextPageToken"); token != "" {
+			fmt.Sscanf(token, "page-%d", &start)
+		}
+		end := min(start+2, len(s.files))
+		page := map[string]any{"files": s.files[start:end], "isTruncated": end < len(s.files)}
+		if end < len(s.files) {
+			page["nextPageToken"] = fmt.Sprintf("page-%d", end)
+		}
+		json.NewEncoder(w).Encode(page)
+		return
+	}
+
+	name, ok := strings.CutSuffix(strings.TrimPrefix(rest, "/"), "/url")
+	if !ok || s.data[name] == nil {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"contentType":          "application/zip",
+		"temporaryDownloadUrl": s.server.URL + "/download/" + name,
+	})
+}
+
+func TestParseOpenDataURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected fetch.OpenDataRef
+		wantErr  bool
+	}{
+		{
+			url:      "opendata://etmaalgegevensKNMIstations/1/etmgeg_260.zip",
+			expected: fetch.OpenDataRef{Dataset: "etmaalgegevensKNMIstations", Version: "1", Filename: "etmgeg_260.zip"},
+		},
+		{
+			url:      "opendata://daily/2/etmgeg_*.zip",
+			expected: fetch.OpenDataRef{Dataset: "daily", Version: "2", Filename: "etmgeg_*.zip"},
+		},
+		{url: "opendata://daily/1", wantErr: true},
+		{url: "opendata://daily//etmgeg_260.zip", wantErr: true},
+		{url: "https://example.com/etmgeg_260.zip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			ref, err := fetch.ParseOpenDataURL(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ref != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, ref)
+			}
+			if ref.String() != tt.url {
+				t.Errorf("expected String() %q, got %q", tt.url, ref.String())
+			}
+		})
+	}
+}
+
+func TestOpenDataClientListFiles(t *testing.T) {
+	files := []fetch.OpenDataFile{
+		{Filename: "etmgeg_240.zip", Size: 10},
+		{Filename: "etmgeg_260.zip", Size: 20},
+		{Filename: "etmgeg_344.zip", Size: 30},
+		{Filename: "etmgeg_380.zip", Size: 40},
+		{Filename: "etmgeg_391.zip", Size: 50},
+	}
+	standIn := newOpenDataStandIn(t, "secret", files, nil)
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		wantErr error
+	}{
+		{name: "lists every page", apiKey: "secret"},
+		{name: "rejected API key", apiKey: "wrong", wantErr: fetch.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fetch.NewOpenDataClient(standIn.server.URL+"/v1", tt.apiKey, nil)
+			got, err := client.ListFiles(context.Background(), "daily", "1")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(files) {
+				t.Fatalf("expected %d files, got %d", len(files), len(got))
+			}
+			for i := range files {
+				if got[i].Filename != files[i].Filename || got[i].Size != files[i].Size {
+					t.Errorf("file %d: expected %+v, got %+v", i, files[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOpenDataClientOpen(t *testing.T) {
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+	older := createTestZip(t, map[string]string{"etmgeg_260.txt": "older"})
+	newer := createTestZip(t, map[string]string{"etmgeg_260.txt": content})
+
+	files := []fetch.OpenDataFile{
+		{Filename: "etmgeg_260_v2.zip", LastModified: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Filename: "etmgeg_260_v1.zip", LastModified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Filename: "etmgeg_260.zip", LastModified: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	data := map[string][]byte{
+		"etmgeg_260.zip":    newer,
+		"etmgeg_260_v1.zip": older,
+		"etmgeg_260_v2.zip": newer,
+	}
+	standIn := newOpenDataStandIn(t, "secret", files, data)
+	client := fetch.NewOpenDataClient(standIn.server.URL+"/v1", "secret", fetch.NewClient(0, 0))
+
+	tests := []struct {
+		name        string
+		url         string
+		errContains string
+	}{
+		{name: "file by name", url: "opendata://daily/1/etmgeg_260.zip"},
+		{name: "newest file matching a pattern", url: "opendata://daily/1/etmgeg_260_v*.zip"},
+		{name: "missing file", url: "opendata://daily/1/etmgeg_999.zip", errContains: "404"},
+		{name: "no file matching a pattern", url: "opendata://daily/1/uurgeg_*.zip", errContains: "no file matching"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := client.Open(context.Background(), tt.url)
+			if tt.errContains != "" {
+				if err == nil {
+					archive.Close()
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			got, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("failed to read archive: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("expected %q, got %q", content, got)
+			}
+		})
+	}
+}
@@ -171,3 +171,145 @@ func TestParseCSVEmptyFields(t *testing.T) {
 		t.Errorf("expected fg=nil (empty), got %v", r.FG)
 	}
 }
+
+func TestParseHeaderMapping(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedCount   int
+		expectedUnknown []string
+		check           func(t *testing.T, r parser.WeatherRecord)
+		wantErr         bool
+		errContains     string
+	}{
+		{
+			name: "subset of columns",
+			input: `# STN,YYYYMMDD,DDVEC,FG,TG,TN,TX,RH
+  344,20240101,  230,   52,   85,   62,  102,   32
+`,
+			expectedCount: 1,
+			check: func(t *testing.T, r parser.WeatherRecord) {
+				if r.StationID != 344 {
+					t.Errorf("expected station_id=344, got %d", r.StationID)
+				}
+				if r.TX == nil || *r.TX != 102 {
+					t.Errorf("expected tx=102, got %v", r.TX)
+				}
+				if r.RH == nil || *r.RH != 32 {
+					t.Errorf("expected rh=32, got %v", r.RH)
+				}
+				if r.EV24 != nil || r.Q != nil {
+					t.Errorf("expected missing ev24 and q to be nil, got %v and %v", r.EV24, r.Q)
+				}
+			},
+		},
+		{
+			name: "columns in different order",
+			input: `# YYYYMMDD,STN,TX,TG
+20240101,  260,  102,   85
+`,
+			expectedCount: 1,
+			check: func(t *testing.T, r parser.WeatherRecord) {
+				if r.StationID != 260 {
+					t.Errorf("expected station_id=260, got %d", r.StationID)
+				}
+				if r.TG == nil || *r.TG != 85 {
+					t.Errorf("expected tg=85, got %v", r.TG)
+				}
+				if r.TX == nil || *r.TX != 102 {
+					t.Errorf("expected tx=102, got %v", r.TX)
+				}
+			},
+		},
+		{
+			name: "unknown columns are reported",
+			input: `# STN,YYYYMMDD,TG,NEWCOL,TX
+  260,20240101,   85,   17,  102
+`,
+			expectedCount:   1,
+			expectedUnknown: []string{"NEWCOL"},
+			check: func(t *testing.T, r parser.WeatherRecord) {
+				if r.TG == nil || *r.TG != 85 {
+					t.Errorf("expected tg=85, got %v", r.TG)
+				}
+				if r.TX == nil || *r.TX != 102 {
+					t.Errorf("expected tx=102, got %v", r.TX)
+				}
+			},
+		},
+		{
+			name: "header without hash and trailing commas",
+			input: `STN,YYYYMMDD,   TG,   RH,
+  550,19510101,   12,    0,
+`,
+			expectedCount: 1,
+			check: func(t *testing.T, r parser.WeatherRecord) {
+				if r.StationID != 550 {
+					t.Errorf("expected station_id=550, got %d", r.StationID)
+				}
+				if r.RH == nil || *r.RH != 0 {
+					t.Errorf("expected rh=0, got %v", r.RH)
+				}
+			},
+		},
+		{
+			name: "trace precipitation is stored as zero with a flag",
+			input: `# STN,YYYYMMDD,   DR,   RH,  RHX,   TG
+  260,20240101,   -1,   -1,   -1,   -1
+  260,20240102,    5,   12,    8,   40
+`,
+			expectedCount: 2,
+			check: func(t *testing.T, r parser.WeatherRecord) {
+				if r.RH == nil || *r.RH != 0 || r.RHX == nil || *r.RHX != 0 || r.DR == nil || *r.DR != 0 {
+					t.Errorf("expected dr, rh and rhx to be 0, got %v, %v and %v", r.DR, r.RH, r.RHX)
+				}
+				if !r.DRTrace || !r.RHTrace || !r.RHXTrace {
+					t.Errorf("expected trace flags to be set, got dr=%v rh=%v rhx=%v", r.DRTrace, r.RHTrace, r.RHXTrace)
+				}
+				// -1 is a real value in other columns
+				if r.TG == nil || *r.TG != -1 {
+					t.Errorf("expected tg=-1, got %v", r.TG)
+				}
+			},
+		},
+		{
+			name: "data line does not match header",
+			input: `# STN,YYYYMMDD,TG,TX
+  260,20240101,   85
+`,
+			wantErr:     true,
+			errContains: "expected 4 columns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Parse(strings.NewReader(tt.input))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				} else if tt.errContains != "" && !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Records) != tt.expectedCount {
+				t.Fatalf("expected %d records, got %d", tt.expectedCount, len(result.Records))
+			}
+
+			if strings.Join(result.UnknownColumns, ",") != strings.Join(tt.expectedUnknown, ",") {
+				t.Errorf("expected unknown columns %v, got %v", tt.expectedUnknown, result.UnknownColumns)
+			}
+
+			if tt.check != nil {
+				tt.check(t, result.Records[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+func TestParsePrecipitation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedCount int
+	}{
+		{
+			name: "knmi file with description and trailing commas",
+			input: `BRON: KONINKLIJK NEDERLANDS METEOROLOGISCH INSTITUUT (KNMI)
+Opmerking: deze reeks is niet gehomogeniseerd
+
+RD        = Etmaalsom van de neerslag (in 0.1 mm) / daily precipitation amount (in 0.1 mm)
+SX        = Code voor sneeuwdek om 08.00 UT / code snow cover at 08.00 UT
+
+STN,YYYYMMDD,   RD,   SX,
+
+  550,19510101,   32,     ,
+  550,19510102,    0,    1,
+`,
+			expectedCount: 2,
+		},
+		{
+			name: "default layout without header",
+			input: `  550,19510101,   32,
+  550,19510102,    0,    1
+`,
+			expectedCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.ParsePrecipitation(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Records) != tt.expectedCount {
+				t.Fatalf("expected %d records, got %d", tt.expectedCount, len(result.Records))
+			}
+
+			first := result.Records[0]
+			if first.StationID != 550 {
+				t.Errorf("expected station_id=550, got %d", first.StationID)
+			}
+			if first.RD == nil || *first.RD != 32 {
+				t.Errorf("expected rd=32, got %v", first.RD)
+			}
+			if first.SX != nil {
+				t.Errorf("expected sx=nil (empty), got %v", first.SX)
+			}
+
+			second := result.Records[1]
+			if second.SX == nil || *second.SX != 1 {
+				t.Errorf("expected sx=1, got %v", second.SX)
+			}
+		})
+	}
+}
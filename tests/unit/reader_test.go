@@ -0,0 +1,194 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedDates   []string
+		expectedUnknown []string
+		expectedStation int
+		wantErr         bool
+		errContains     string
+	}{
+		{
+			name: "reads records one at a time",
+			input: `# STN         LON(east)   LAT(north)  ALT(m)      NAME
+# 260         5.180       52.100      1.90        De Bilt
+# STN,YYYYMMDD,TG,NEWCOL
+  260,20240101,   85,    1
+  260,20240102,   90,    2
+  260,20240103,   88,    3
+`,
+			expectedDates:   []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+			expectedUnknown: []string{"NEWCOL"},
+			expectedStation: 260,
+		},
+		{
+			name: "stops at invalid line after earlier records",
+			input: `# STN,YYYYMMDD,TG
+  260,20240101,   85
+  260,2024XX02,   90
+  260,20240103,   88
+`,
+			expectedDates: []string{"2024-01-01"},
+			wantErr:       true,
+			errContains:   "line 3",
+		},
+		{
+			name:          "empty input",
+			input:         "",
+			expectedDates: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := parser.NewReader(strings.NewReader(tt.input))
+
+			var dates []string
+			for reader.Next() {
+				dates = append(dates, reader.Record().Date.Format("2006-01-02"))
+			}
+			err := reader.Err()
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(dates, ",") != strings.Join(tt.expectedDates, ",") {
+				t.Errorf("expected dates %v, got %v", tt.expectedDates, dates)
+			}
+			if strings.Join(reader.UnknownColumns(), ",") != strings.Join(tt.expectedUnknown, ",") {
+				t.Errorf("expected unknown columns %v, got %v", tt.expectedUnknown, reader.UnknownColumns())
+			}
+			if tt.expectedStation != 0 {
+				stations := reader.Stations()
+				if len(stations) != 1 || stations[0].ID != tt.expectedStation {
+					t.Errorf("expected station %d, got %+v", tt.expectedStation, stations)
+				}
+			}
+		})
+	}
+}
+
+func TestReaderParseMode(t *testing.T) {
+	const input = `# STN,YYYYMMDD,TG,RH
+  260,20240101,   85,    3
+  260,20240102,  abc,    0
+  260,2024XX03,   88,    1
+  260,20240104,   90
+  260,20240105,   92,    2
+`
+
+	tests := []struct {
+		name            string
+		mode            parser.ParseMode
+		expectedDates   []string
+		expectedRejects []string
+		errContains     string
+	}{
+		{
+			name:          "strict fails on a malformed measurement",
+			mode:          parser.ModeStrict,
+			expectedDates: []string{"2024-01-01"},
+			errContains:   `line 3, column TG: invalid value "abc"`,
+		},
+		{
+			name:          "lenient skips malformed lines",
+			mode:          parser.ModeLenient,
+			expectedDates: []string{"2024-01-01", "2024-01-05"},
+			expectedRejects: []string{
+				`line 3, column TG: invalid value "abc" (expected an integer)`,
+				`line 4, column YYYYMMDD: invalid date format "2024XX03" (expected YYYYMMDD)`,
+				`line 5: expected 4 columns, got 3`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := parser.NewReader(strings.NewReader(input))
+			reader.SetMode(tt.mode)
+
+			var dates []string
+			for reader.Next() {
+				dates = append(dates, reader.Record().Date.Format("2006-01-02"))
+			}
+			err := reader.Err()
+
+			if tt.errContains != "" {
+				if err == nil {
+					t.Error("expected error, got nil")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(dates, ",") != strings.Join(tt.expectedDates, ",") {
+				t.Errorf("expected dates %v, got %v", tt.expectedDates, dates)
+			}
+
+			rejects := reader.TakeRejects()
+			if left := reader.Rejects(); len(left) != 0 {
+				t.Errorf("expected no rejects after taking them, got %d", len(left))
+			}
+			if len(rejects) != len(tt.expectedRejects) {
+				t.Fatalf("expected %d rejects, got %d", len(tt.expectedRejects), len(rejects))
+			}
+			for i, rej := range rejects {
+				if rej.Error() != tt.expectedRejects[i] {
+					t.Errorf("reject %d: expected %q, got %q", i, tt.expectedRejects[i], rej.Error())
+				}
+				if !strings.Contains(input, rej.Text) {
+					t.Errorf("reject %d: text %q is not a line of the input", i, rej.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestParseModeFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected parser.ParseMode
+		wantErr  bool
+	}{
+		{name: "strict", expected: parser.ModeStrict},
+		{name: "lenient", expected: parser.ModeLenient},
+		{name: "Lenient", expected: parser.ModeLenient},
+		{name: "relaxed", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := parser.ParseModeFromName(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", mode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mode != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, mode)
+			}
+		})
+	}
+}
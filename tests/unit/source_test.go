@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/config"
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+// memorySource serves data files held in memory, standing in for a
+// provider registered outside the fetch package.
+type memorySource struct {
+	files map[string]string
+}
+
+func (m *memorySource) Fetch(ctx context.Context, url string, since fetch.Validators) (*fetch.Archive, error) {
+	data, ok := m.files[url]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return fetch.OpenReader(strings.NewReader(data))
+}
+
+func (m *memorySource) Download(ctx context.Context, url string) ([]byte, error) {
+	return []byte(m.files[url]), nil
+}
+
+func TestNewSource(t *testing.T) {
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+	zipData := createTestZip(t, map[string]string{"etmgeg_260.txt": content})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(zipData)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "etmgeg_260.zip")
+	if err := os.WriteFile(path, zipData, 0o644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	fileURL, err := fetch.FileURL(path)
+	if err != nil {
+		t.Fatalf("failed to build file URL: %v", err)
+	}
+
+	fetch.RegisterSource("memory", []string{"memory"}, func(opts fetch.SourceOptions) (fetch.Source, error) {
+		return &memorySource{files: map[string]string{"memory://etmgeg_260.txt": content}}, nil
+	})
+
+	tests := []struct {
+		name        string
+		url         string
+		opts        fetch.SourceOptions
+		errContains string
+	}{
+		{name: "HTTP archive", url: server.URL + "/etmgeg_260.zip"},
+		{name: "local file", url: fileURL},
+		{name: "standard input", url: fetch.StdinURL, opts: fetch.SourceOptions{Stdin: bytes.NewReader(zipData)}},
+		{name: "registered source", url: "memory://etmgeg_260.txt"},
+		{name: "Open Data API without API key", url: "opendata://daily/1/etmgeg_260.zip", errContains: "API key"},
+		{name: "unknown scheme", url: "ftp://example.com/etmgeg_260.zip", errContains: "no source"},
+		{name: "path without scheme", url: "etmgeg_260.zip", errContains: "no source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := fetch.NewSource(tt.url, tt.opts)
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			archive, err := source.Fetch(context.Background(), tt.url, fetch.Validators{})
+			if err != nil {
+				t.Fatalf("failed to fetch: %v", err)
+			}
+			defer archive.Close()
+
+			got, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("failed to read data: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("expected %q, got %q", content, got)
+			}
+		})
+	}
+}
+
+func TestNewNamedSource(t *testing.T) {
+	opts := fetch.SourceOptions{APIKey: "secret", OpenDataDataset: "daily", OpenDataVersion: "1"}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Open Data API locates CDN files", source: "opendata", expected: "opendata://daily/1/etmgeg_{station}.zip"},
+		{name: "CDN is not a locator", source: fetch.DefaultSourceName},
+		{name: "unknown source", source: "ftp", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := fetch.NewNamedSource(tt.source, opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			locator, ok := source.(fetch.Locator)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("expected %s not to be a locator", tt.source)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("expected %s to be a locator", tt.source)
+			}
+			if got := locator.Locate(config.DefaultKNMIDataURL); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSourceOffline(t *testing.T) {
+	const url = "https://cdn.example/etmgeg_260.zip"
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+
+	cache := fetch.NewCache(t.TempDir())
+	if _, err := cache.Store(url, bytes.NewReader(createTestZip(t, map[string]string{"etmgeg_260.txt": content})), time.Now()); err != nil {
+		t.Fatalf("failed to store archive: %v", err)
+	}
+
+	client := fetch.NewClient(0, 0)
+	client.Cache = cache
+	opts := fetch.SourceOptions{Client: client, Offline: true}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "cached archive", url: url},
+		{name: "archive not cached", url: "https://cdn.example/etmgeg_344.zip", wantErr: fetch.ErrNotCached},
+		{name: "Open Data API without API key", url: "opendata://daily/1/etmgeg_260.zip", wantErr: fetch.ErrNotCached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := fetch.NewSource(tt.url, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			archive, err := source.Fetch(context.Background(), tt.url, fetch.Validators{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to fetch: %v", err)
+			}
+			defer archive.Close()
+
+			got, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("failed to read data: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("expected %q, got %q", content, got)
+			}
+		})
+	}
+}
+
+func TestSourceOfflineDownload(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(strings.Repeat("0", 64)))
+	}))
+	defer server.Close()
+
+	opts := fetch.SourceOptions{Client: fetch.NewClient(0, 0), Offline: true, OpenDataURL: server.URL}
+
+	for _, url := range []string{server.URL + "/etmgeg_260.zip.sha256", "opendata://daily/1/etmgeg_260.zip.sha256"} {
+		t.Run(url, func(t *testing.T) {
+			source, err := fetch.NewSource(url, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := source.Download(context.Background(), url); !errors.Is(err, fetch.ErrOffline) {
+				t.Errorf("expected ErrOffline, got %v", err)
+			}
+		})
+	}
+	if requests != 0 {
+		t.Errorf("expected no requests in offline mode, got %d", requests)
+	}
+}
@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+func TestParseStationMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []parser.Station
+	}{
+		{
+			name: "daily file station block",
+			input: `# SOURCE: ROYAL NETHERLANDS METEOROLOGICAL INSTITUTE (KNMI)
+#
+# STN         LON(east)   LAT(north)  ALT(m)      NAME
+# 260         5.180       52.100      1.90        De Bilt
+#
+# STN,YYYYMMDD,TG
+  260,20240101,   85
+`,
+			expected: []parser.Station{
+				{ID: 260, Longitude: 5.18, Latitude: 52.1, Altitude: 1.9, Name: "De Bilt"},
+			},
+		},
+		{
+			name: "station numbers followed by a colon",
+			input: `# STN         LON(east)   LAT(north)  ALT(m)      NAME
+# 240:        4.790       52.318      -3.30       Schiphol
+# 260:        5.180       52.100      1.90        De Bilt
+# STN,YYYYMMDD,TG
+  240,20240101,   90
+`,
+			expected: []parser.Station{
+				{ID: 240, Longitude: 4.79, Latitude: 52.318, Altitude: -3.3, Name: "Schiphol"},
+				{ID: 260, Longitude: 5.18, Latitude: 52.1, Altitude: 1.9, Name: "De Bilt"},
+			},
+		},
+		{
+			name: "no station block",
+			input: `# STN,YYYYMMDD,TG
+  260,20240101,   85
+`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(result.Stations) != len(tt.expected) {
+				t.Fatalf("expected %d stations, got %d: %+v", len(tt.expected), len(result.Stations), result.Stations)
+			}
+			for i, expected := range tt.expected {
+				if result.Stations[i] != expected {
+					t.Errorf("station %d: expected %+v, got %+v", i, expected, result.Stations[i])
+				}
+			}
+			if len(result.Records) != 1 {
+				t.Errorf("expected 1 record, got %d", len(result.Records))
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+func TestWeatherColumnsMatchDefaultColumns(t *testing.T) {
+	// Every measurement column is described, in file order
+	measurements := parser.DefaultColumns[2:]
+	if len(parser.WeatherColumns) != len(measurements) {
+		t.Fatalf("expected %d described columns, got %d", len(measurements), len(parser.WeatherColumns))
+	}
+	for i, c := range parser.WeatherColumns {
+		if c.Name != measurements[i] {
+			t.Errorf("column %d: expected %s, got %s", i, measurements[i], c.Name)
+		}
+		if c.Description == "" || c.Divisor <= 0 {
+			t.Errorf("column %s: incomplete description %+v", c.Name, c)
+		}
+	}
+}
+
+func TestLookupWeatherColumn(t *testing.T) {
+	tests := []struct {
+		name        string
+		expectFound bool
+		unit        string
+	}{
+		{"TG", true, "°C"},
+		{"tg", true, "°C"},
+		{"rh", true, "mm"},
+		{"fxxh", true, ""},
+		{"rh_trace", false, ""},
+		{"STN", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := parser.LookupWeatherColumn(tt.name)
+			if ok != tt.expectFound {
+				t.Fatalf("expected found=%v, got %v", tt.expectFound, ok)
+			}
+			if info.Unit != tt.unit {
+				t.Errorf("expected unit %q, got %q", tt.unit, info.Unit)
+			}
+		})
+	}
+}
+
+func TestObservation(t *testing.T) {
+	rec := parser.WeatherRecord{
+		StationID: 260,
+		Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		DDVEC:     intPtr(230),
+		FG:        intPtr(52),
+		TG:        intPtr(185),
+		TN:        intPtr(-998),
+		SQ:        intPtr(87),
+		Q:         intPtr(1520),
+		RH:        intPtr(0),
+		RHTrace:   true,
+		PG:        intPtr(10123),
+		UG:        intPtr(81),
+	}
+
+	obs := rec.Observation()
+
+	if obs.StationID != 260 || !obs.Date.Equal(rec.Date) {
+		t.Errorf("expected station 260 on %v, got %d on %v", rec.Date, obs.StationID, obs.Date)
+	}
+
+	tests := []struct {
+		name     string
+		value    *float64
+		expected float64
+	}{
+		{"wind direction", obs.WindDirection, 230},
+		{"mean wind speed", obs.MeanWindSpeed, 5.2},
+		{"mean temperature", obs.MeanTemperature, 18.5},
+		{"negative temperature", obs.MinTemperature, -99.8},
+		{"sunshine duration", obs.SunshineDuration, 8.7},
+		{"global radiation", obs.GlobalRadiation, 1520},
+		{"trace precipitation", obs.Precipitation, 0},
+		{"mean pressure", obs.MeanPressure, 1012.3},
+		{"mean humidity", obs.MeanHumidity, 81},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value == nil {
+				t.Fatalf("expected %v, got nil", tt.expected)
+			}
+			if *tt.value != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, *tt.value)
+			}
+		})
+	}
+
+	if !obs.PrecipitationTrace {
+		t.Error("expected precipitation trace flag to be copied")
+	}
+	if obs.MaxTemperature != nil || obs.Evapotranspiration != nil {
+		t.Errorf("expected missing measurements to be nil, got %v and %v", obs.MaxTemperature, obs.Evapotranspiration)
+	}
+}
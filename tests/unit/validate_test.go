@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/parser"
+	"github.com/harrybawsac/knmi-go/internal/validate"
+)
+
+// plausibleRecord returns a record that passes every validation rule.
+func plausibleRecord() parser.WeatherRecord {
+	return parser.WeatherRecord{
+		StationID: 260,
+		Date:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		DDVEC:     intPtr(230),
+		FHN:       intPtr(20),
+		FG:        intPtr(40),
+		FHX:       intPtr(70),
+		FHXH:      intPtr(14),
+		FXX:       intPtr(120),
+		TN:        intPtr(120),
+		TG:        intPtr(185),
+		TX:        intPtr(240),
+		T10NH:     intPtr(6),
+		RH:        intPtr(12),
+		RHX:       intPtr(8),
+		PN:        intPtr(10100),
+		PG:        intPtr(10120),
+		PX:        intPtr(10140),
+		NG:        intPtr(6),
+		UN:        intPtr(55),
+		UG:        intPtr(75),
+		UX:        intPtr(95),
+	}
+}
+
+func TestValidatorCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(r *parser.WeatherRecord)
+		expected    []string
+		msgContains string
+	}{
+		{"plausible record", func(r *parser.WeatherRecord) {}, nil, ""},
+		{"missing values pass", func(r *parser.WeatherRecord) { r.TG, r.UG = nil, nil }, nil, ""},
+		{"wind direction above 360", func(r *parser.WeatherRecord) { r.DDVEC = intPtr(361) }, []string{"wind-direction"}, "DDVEC 361 °"},
+		{"humidity above 100", func(r *parser.WeatherRecord) { r.UX = intPtr(101) }, []string{"humidity-range"}, "UX 101 % outside 0 % to 100 %"},
+		{"cloud cover above 9", func(r *parser.WeatherRecord) { r.NG = intPtr(10) }, []string{"cloud-cover"}, "NG 10 okta"},
+		{"hour outside 1-24", func(r *parser.WeatherRecord) { r.FHXH = intPtr(25) }, []string{"hour-range"}, "FHXH 25"},
+		{"invalid T10N period", func(r *parser.WeatherRecord) { r.T10NH = intPtr(7) }, []string{"t10n-period"}, "not one of 6, 12, 18, 24"},
+		{"negative precipitation", func(r *parser.WeatherRecord) { r.RH, r.RHX = intPtr(-5), nil }, []string{"non-negative"}, "RH -0.5 mm below 0.0 mm"},
+		{"TN above TX", func(r *parser.WeatherRecord) { r.TN, r.TG = intPtr(250), nil }, []string{"temperature-order"}, "TN 25.0 °C > TX 24.0 °C"},
+		{"TG below TN", func(r *parser.WeatherRecord) { r.TG = intPtr(100) }, []string{"temperature-order"}, "TN 12.0 °C > TG 10.0 °C"},
+		{"gust below hourly maximum", func(r *parser.WeatherRecord) { r.FXX = intPtr(60) }, []string{"wind-speed-order"}, "FHX 7.0 m/s > FXX 6.0 m/s"},
+		{"hourly precipitation above daily", func(r *parser.WeatherRecord) { r.RHX = intPtr(20) }, []string{"precipitation-order"}, ""},
+		{
+			name: "several rules",
+			modify: func(r *parser.WeatherRecord) {
+				r.UG = intPtr(120)
+				r.PN = intPtr(10200)
+			},
+			expected: []string{"humidity-range", "pressure-order", "humidity-order"},
+		},
+	}
+
+	validator, err := validate.New(validate.SeverityWarn, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := plausibleRecord()
+			tt.modify(&rec)
+
+			violations := validator.Check(&rec)
+			var rules []string
+			for _, v := range violations {
+				rules = append(rules, v.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("expected rules %v, got %v", tt.expected, violations)
+			}
+			if tt.msgContains != "" && !strings.Contains(violations[0].Message, tt.msgContains) {
+				t.Errorf("expected message containing %q, got %q", tt.msgContains, violations[0].Message)
+			}
+		})
+	}
+}
+
+func TestValidatorValidate(t *testing.T) {
+	plausible := plausibleRecord()
+	badHumidity := plausibleRecord()
+	badHumidity.UG, badHumidity.UX = intPtr(105), nil
+	badTemperature := plausibleRecord()
+	badTemperature.TN = intPtr(300)
+	both := plausibleRecord()
+	both.UG, both.UX = intPtr(105), nil
+	both.TN = intPtr(300)
+
+	validator, err := validate.New(validate.SeverityQuarantine, map[string]validate.Severity{
+		"humidity-range":    validate.SeverityWarn,
+		"temperature-order": validate.SeverityReject,
+		"cloud-cover":       validate.SeverityOff,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := validator.Validate([]parser.WeatherRecord{plausible, badHumidity, badTemperature, both})
+
+	// Warned records are stored along with the plausible ones
+	if len(result.Accepted) != 2 || len(result.Warned) != 1 {
+		t.Errorf("expected 2 accepted and 1 warned, got %d and %d", len(result.Accepted), len(result.Warned))
+	}
+	// The highest severity decides what happens to a record
+	if len(result.Rejected) != 2 || len(result.Quarantined) != 0 {
+		t.Errorf("expected 2 rejected and 0 quarantined, got %d and %d", len(result.Rejected), len(result.Quarantined))
+	}
+	if len(result.Rejected) == 2 && len(result.Rejected[1].Violations) != 2 {
+		t.Errorf("expected both violations to be kept, got %v", result.Rejected[1].Violations)
+	}
+
+	// Disabled rules do not fire
+	noisy := plausibleRecord()
+	noisy.NG = intPtr(12)
+	if violations := validator.Check(&noisy); len(violations) != 0 {
+		t.Errorf("expected disabled rule to be skipped, got %v", violations)
+	}
+}
+
+func TestValidatorUnknownRule(t *testing.T) {
+	_, err := validate.New(validate.SeverityWarn, map[string]validate.Severity{"no-such-rule": validate.SeverityOff})
+	if err == nil || !strings.Contains(err.Error(), `unknown validation rule "no-such-rule"`) {
+		t.Errorf("expected unknown rule error, got %v", err)
+	}
+}
+
+func TestSeverityFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected validate.Severity
+		wantErr  bool
+	}{
+		{"off", validate.SeverityOff, false},
+		{"warn", validate.SeverityWarn, false},
+		{"Quarantine", validate.SeverityQuarantine, false},
+		{"reject", validate.SeverityReject, false},
+		{"drop", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			severity, err := validate.SeverityFromName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && severity != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+func TestParseChecksum(t *testing.T) {
+	const hash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "bare hash",
+			data:     hash + "\n",
+			expected: hash,
+		},
+		{
+			name:     "sha256sum output",
+			data:     hash + "  etmgeg_260.zip\n",
+			expected: hash,
+		},
+		{
+			name:     "BSD tag output",
+			data:     "SHA256 (etmgeg_260.zip) = " + hash + "\n",
+			expected: hash,
+		},
+		{
+			name:     "uppercase hash",
+			data:     strings.ToUpper(hash),
+			expected: hash,
+		},
+		{
+			name:    "no hash",
+			data:    "<html>Not Found</html>",
+			wantErr: true,
+		},
+		{
+			name:    "hash too short",
+			data:    hash[:40],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetch.ParseChecksum([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestArchiveVerify(t *testing.T) {
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+	zipData := createTestZip(t, map[string]string{"etmgeg_260.txt": content})
+	wrong := strings.Repeat("0", 64)
+
+	open := map[string]func(t *testing.T, data []byte) *fetch.Archive{
+		"file": func(t *testing.T, data []byte) *fetch.Archive {
+			path := filepath.Join(t.TempDir(), "data")
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			archive, err := fetch.OpenFile(path)
+			if err != nil {
+				t.Fatalf("failed to open file: %v", err)
+			}
+			return archive
+		},
+		"reader": func(t *testing.T, data []byte) *fetch.Archive {
+			archive, err := fetch.OpenReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to open reader: %v", err)
+			}
+			return archive
+		},
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "zip archive matches",
+			data:     zipData,
+			expected: sha256Hex(zipData),
+		},
+		{
+			name:     "uppercase hash matches",
+			data:     zipData,
+			expected: strings.ToUpper(sha256Hex(zipData)),
+		},
+		{
+			name:     "zip archive mismatch",
+			data:     zipData,
+			expected: wrong,
+			wantErr:  true,
+		},
+		{
+			name:     "text file matches",
+			data:     []byte(content),
+			expected: sha256Hex([]byte(content)),
+		},
+		{
+			name:     "text file mismatch",
+			data:     []byte(content),
+			expected: wrong,
+			wantErr:  true,
+		},
+	}
+
+	for source, openArchive := range open {
+		for _, tt := range tests {
+			t.Run(source+"/"+tt.name, func(t *testing.T) {
+				archive := openArchive(t, tt.data)
+				defer archive.Close()
+
+				err := archive.Verify(tt.expected)
+
+				if tt.wantErr {
+					if !errors.Is(err, fetch.ErrChecksumMismatch) {
+						t.Errorf("expected checksum mismatch, got %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := archive.SHA256(); got != sha256Hex(tt.data) {
+					t.Errorf("expected SHA-256 %s, got %s", sha256Hex(tt.data), got)
+				}
+				data, err := io.ReadAll(archive)
+				if err != nil {
+					t.Fatalf("failed to read archive: %v", err)
+				}
+				if string(data) != content {
+					t.Errorf("expected content %q, got %q", content, data)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenZipCorrupt(t *testing.T) {
+	const content = "# STN,YYYYMMDD,TG\n  260,20240101,   85\n"
+	stored := createStoredZip(t, "etmgeg_260.txt", []byte(content), uint64(len(content)))
+
+	// Flip a byte of the stored file data, which follows the local header
+	corrupted := bytes.Clone(stored)
+	offset := bytes.Index(corrupted, []byte("20240101"))
+	if offset < 0 {
+		t.Fatal("test data not found in stored zip")
+	}
+	corrupted[offset] = '9'
+
+	tests := []struct {
+		name        string
+		data        []byte
+		errContains string
+	}{
+		{
+			name:        "truncated archive",
+			data:        stored[:len(stored)/2],
+			errContains: "corrupt or truncated",
+		},
+		{
+			name:        "not a zip archive",
+			data:        []byte("PK\x03\x04 garbage"),
+			errContains: "corrupt or truncated",
+		},
+		{
+			name:        "understated size",
+			data:        createStoredZip(t, "etmgeg_260.txt", []byte(content), 10),
+			errContains: "size does not match",
+		},
+		{
+			name:        "CRC mismatch",
+			data:        corrupted,
+			errContains: "CRC-32 mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fetch.ExtractZip(tt.data)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+			}
+		})
+	}
+}
+
+func TestMaxExtractedSize(t *testing.T) {
+	content := strings.Repeat("  260,20240101,   85\n", 10)
+
+	tests := []struct {
+		name    string
+		limit   int64
+		data    []byte
+		wantErr bool
+	}{
+		{
+			name:  "within limit",
+			limit: int64(len(content)),
+			data:  createTestZip(t, map[string]string{"etmgeg_260.txt": content}),
+		},
+		{
+			name:  "default limit",
+			limit: 0,
+			data:  createTestZip(t, map[string]string{"etmgeg_260.txt": content}),
+		},
+		{
+			name:  "limit disabled",
+			limit: -1,
+			data:  createTestZip(t, map[string]string{"etmgeg_260.txt": content}),
+		},
+		{
+			name:    "declared size over limit",
+			limit:   int64(len(content)) - 1,
+			data:    createTestZip(t, map[string]string{"etmgeg_260.txt": content}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "etmgeg_260.zip")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatalf("failed to write archive: %v", err)
+			}
+
+			client := &fetch.Client{MaxExtractedSize: tt.limit}
+			archive, err := client.OpenFile(path)
+			if tt.wantErr {
+				if !errors.Is(err, fetch.ErrTooLarge) {
+					t.Errorf("expected ErrTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer archive.Close()
+
+			got, err := io.ReadAll(archive)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != content {
+				t.Errorf("expected %d bytes, got %d", len(content), len(got))
+			}
+		})
+	}
+}
+
+// createStoredZip creates a zip archive holding one uncompressed file whose
+// header declares the given uncompressed size.
+func createStoredZip(t *testing.T, name string, content []byte, size uint64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	fw, err := w.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: size,
+	})
+	if err != nil {
+		t.Fatalf("failed to create file in zip: %v", err)
+	}
+	if _, err := fw.Write(content); err != nil {
+		t.Fatalf("failed to write to zip: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close zip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// sha256Hex returns the hex-encoded SHA-256 hash of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestChangedColumns(t *testing.T) {
+	date := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	base := parser.WeatherRecord{
+		StationID: 260,
+		Date:      date,
+		TG:        intPtr(185),
+		RH:        intPtr(12),
+		EV24:      nil,
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(r *parser.WeatherRecord)
+		expected []string
+	}{
+		{"identical records", func(r *parser.WeatherRecord) {}, nil},
+		{"same value different pointer", func(r *parser.WeatherRecord) { r.TG = intPtr(185) }, nil},
+		{"value changed", func(r *parser.WeatherRecord) { r.TG = intPtr(190) }, []string{"tg"}},
+		{"value removed", func(r *parser.WeatherRecord) { r.RH = nil }, []string{"rh"}},
+		{"value added", func(r *parser.WeatherRecord) { r.EV24 = intPtr(31) }, []string{"ev24"}},
+		{
+			name: "trace flag changed",
+			modify: func(r *parser.WeatherRecord) {
+				r.RH = intPtr(0)
+				r.RHTrace = true
+			},
+			expected: []string{"rh", "rh_trace"},
+		},
+		{
+			name: "multiple changes in column order",
+			modify: func(r *parser.WeatherRecord) {
+				r.EV24 = intPtr(31)
+				r.TG = intPtr(190)
+			},
+			expected: []string{"tg", "ev24"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updated := base
+			tc.modify(&updated)
+
+			changed := db.ChangedColumns(&base, &updated)
+			if !reflect.DeepEqual(changed, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, changed)
+			}
+		})
+	}
+}
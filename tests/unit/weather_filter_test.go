@@ -0,0 +1,69 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/harrybawsac/knmi-go/internal/db"
+	"github.com/harrybawsac/knmi-go/internal/parser"
+)
+
+func TestFilterAfterLatest(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatalf("invalid test date %q: %v", s, err)
+		}
+		return d
+	}
+
+	records := []parser.WeatherRecord{
+		{StationID: 240, Date: day("2024-01-01")},
+		{StationID: 240, Date: day("2024-01-02")},
+		{StationID: 260, Date: day("2024-01-01")},
+		{StationID: 260, Date: day("2024-01-02")},
+		{StationID: 260, Date: day("2024-01-03")},
+		{StationID: 380, Date: day("2024-01-01")},
+	}
+
+	testCases := []struct {
+		name          string
+		latest        map[int]time.Time
+		expectedCount int
+	}{
+		{"empty database keeps everything", map[int]time.Time{}, 6},
+		{"nil map keeps everything", nil, 6},
+		{
+			name: "each station uses its own cutoff",
+			latest: map[int]time.Time{
+				240: day("2023-12-31"),
+				260: day("2024-01-02"),
+			},
+			// 240: both records, 260: only 01-03, 380: unknown station so all
+			expectedCount: 4,
+		},
+		{
+			name: "lagging station is not cut off by a newer one",
+			latest: map[int]time.Time{
+				240: day("2024-01-05"),
+				260: day("2023-06-01"),
+				380: day("2024-01-05"),
+			},
+			expectedCount: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := db.FilterAfterLatest(records, tc.latest)
+			if len(filtered) != tc.expectedCount {
+				t.Errorf("expected %d records, got %d", tc.expectedCount, len(filtered))
+			}
+			for _, rec := range filtered {
+				if cutoff, ok := tc.latest[rec.StationID]; ok && !rec.Date.After(cutoff) {
+					t.Errorf("station %d record %s should have been filtered", rec.StationID, rec.Date.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package unit
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/harrybawsac/knmi-go/internal/fetch"
+)
+
+func TestExtractZipFiles(t *testing.T) {
+	zipData := createTestZip(t, map[string]string{
+		"etmgeg_260.txt":      "station 260",
+		"etmgeg_344.txt":      "station 344",
+		"data/uurgeg_260.txt": "hourly 260",
+		"README.md":           "readme",
+	})
+
+	tests := []struct {
+		name        string
+		pattern     string
+		expected    []string
+		errContains string
+	}{
+		{
+			name:     "default pattern returns every text file",
+			pattern:  "",
+			expected: []string{"data/uurgeg_260.txt", "etmgeg_260.txt", "etmgeg_344.txt"},
+		},
+		{
+			name:     "glob selects matching files",
+			pattern:  "etmgeg_*.txt",
+			expected: []string{"etmgeg_260.txt", "etmgeg_344.txt"},
+		},
+		{
+			name:     "glob selects a single file",
+			pattern:  "etmgeg_344.txt",
+			expected: []string{"etmgeg_344.txt"},
+		},
+		{
+			name:     "pattern with directory matches full name",
+			pattern:  "data/*.txt",
+			expected: []string{"data/uurgeg_260.txt"},
+		},
+		{
+			name:        "no matching file",
+			pattern:     "*.csv",
+			errContains: `no file matching "*.csv"`,
+		},
+		{
+			name:        "invalid pattern",
+			pattern:     "[",
+			errContains: "invalid data file pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := fetch.ExtractZipFiles(zipData, tt.pattern)
+			if tt.errContains != "" {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Name)
+				if want := zipTestContent(f.Name); string(f.Data) != want {
+					t.Errorf("%s: expected content %q, got %q", f.Name, want, f.Data)
+				}
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected files %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestArchiveNextFile(t *testing.T) {
+	zipData := createTestZip(t, map[string]string{
+		"etmgeg_260.txt": "station 260",
+		"etmgeg_344.txt": "station 344",
+		"README.md":      "readme",
+	})
+	path := filepath.Join(t.TempDir(), "etmgeg.zip")
+	if err := os.WriteFile(path, zipData, 0o644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "reads every data file",
+			pattern:  fetch.DefaultDataFilePattern,
+			expected: []string{"etmgeg_260.txt", "etmgeg_344.txt"},
+		},
+		{
+			name:     "reads selected data file",
+			pattern:  "*_344.txt",
+			expected: []string{"etmgeg_344.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fetch.Client{DataFilePattern: tt.pattern}
+			archive, err := client.OpenFile(path)
+			if err != nil {
+				t.Fatalf("failed to open archive: %v", err)
+			}
+			defer archive.Close()
+
+			var names []string
+			for {
+				content, err := io.ReadAll(archive)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", archive.Name(), err)
+				}
+				if want := zipTestContent(archive.Name()); string(content) != want {
+					t.Errorf("%s: expected content %q, got %q", archive.Name(), want, content)
+				}
+				names = append(names, archive.Name())
+
+				err = archive.NextFile()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("failed to open next file: %v", err)
+				}
+			}
+
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected files %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	t.Run("text file has a single data file", func(t *testing.T) {
+		textPath := filepath.Join(t.TempDir(), "etmgeg_260.txt")
+		if err := os.WriteFile(textPath, []byte("station 260"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		archive, err := fetch.OpenFile(textPath)
+		if err != nil {
+			t.Fatalf("failed to open file: %v", err)
+		}
+		defer archive.Close()
+
+		if archive.Name() != "etmgeg_260.txt" {
+			t.Errorf("expected name etmgeg_260.txt, got %q", archive.Name())
+		}
+		if err := archive.NextFile(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	})
+}
+
+// zipTestContent returns the content the zip file tests store under name.
+func zipTestContent(name string) string {
+	contents := map[string]string{
+		"etmgeg_260.txt":      "station 260",
+		"etmgeg_344.txt":      "station 344",
+		"data/uurgeg_260.txt": "hourly 260",
+	}
+	return contents[name]
+}